@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pixel8labs/logtrace"
 	"github.com/pixel8labs/logtrace/log"
 	restmiddleware "github.com/pixel8labs/logtrace/middleware"
 	"github.com/pixel8labs/logtrace/trace"
@@ -13,16 +18,20 @@ import (
 
 func main() {
 	// Init logger.
-	log.Init("logtrace-example", "local")
+	if err := log.Init("logtrace-example", "local"); err != nil {
+		panic(err)
+	}
 
 	// Init tracer.
-	trace.InitTracer()
+	if _, err := trace.InitTracer(trace.WithLogger(log.Logr())); err != nil {
+		panic(err)
+	}
 
 	e := echo.New()
 
 	e.Use(
 		// Trace middleware comes first so that the logger has the trace_id and span_id.
-		restmiddleware.TracingMiddleware("logtrace-example"),
+		restmiddleware.Tracer("logtrace-example"),
 		restmiddleware.Logger(),
 	)
 
@@ -31,9 +40,25 @@ func main() {
 		return c.String(http.StatusOK, "OK")
 	})
 
-	if err := e.Start("localhost:8080"); err != nil {
-		if !errors.Is(err, http.ErrServerClosed) {
-			log.Fatal(context.Background(), err, nil, "Failed to start server")
+	go func() {
+		if err := e.Start("localhost:8080"); err != nil {
+			if !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(context.Background(), err, nil, "Failed to start server")
+			}
 		}
+	}()
+
+	// Wait for the termination signal, then flush the pending spans & logs before exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Error(shutdownCtx, err, nil, "Failed to shutdown server")
+	}
+	if err := logtrace.Shutdown(shutdownCtx); err != nil {
+		log.Error(shutdownCtx, err, nil, "Failed to flush logs & traces")
 	}
 }
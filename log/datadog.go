@@ -0,0 +1,449 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	dataDogIntakePath = "/api/v2/logs"
+	// Limits of the Datadog logs intake API.
+	dataDogMaxBatchEntries = 1000
+	dataDogMaxBatchBytes   = 5 * 1024 * 1024
+	// dataDogMaxPendingBatches is how many batches can wait while one is sent or retried, see dataDogWriter.run.
+	dataDogMaxPendingBatches = 4
+)
+
+type DataDogDropPolicy int
+
+const (
+	// DropNewest drops the incoming log line when the buffer is full.
+	DropNewest DataDogDropPolicy = iota
+	// DropOldest drops the oldest buffered log line to make room for the incoming one.
+	DropOldest
+)
+
+type dataDogConfig struct {
+	apiKey        string
+	intakeURL     string
+	serviceName   string
+	env           string
+	hostname      string
+	tags          []string
+	bufferSize    int
+	dropPolicy    DataDogDropPolicy
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+	client        *http.Client
+}
+
+type DataDogOptFn func(config *dataDogConfig)
+
+// WithDataDogTags adds tags (e.g. "team:payments") to every log line, on top of the env tag.
+func WithDataDogTags(tags ...string) DataDogOptFn {
+	return func(config *dataDogConfig) {
+		config.tags = append(config.tags, tags...)
+	}
+}
+
+// WithDataDogBuffer sets how many log lines can be buffered before they're dropped following the policy.
+// Defaults to 10000 lines & DropNewest.
+func WithDataDogBuffer(size int, policy DataDogDropPolicy) DataDogOptFn {
+	return func(config *dataDogConfig) {
+		config.bufferSize = size
+		config.dropPolicy = policy
+	}
+}
+
+// WithDataDogFlushInterval sets how often the buffered log lines are sent. Defaults to 5 seconds.
+func WithDataDogFlushInterval(interval time.Duration) DataDogOptFn {
+	return func(config *dataDogConfig) {
+		config.flushInterval = interval
+	}
+}
+
+// WithDataDogRetry sets how many times a failed batch is retried, with exponential backoff starting at backoff.
+// Defaults to 3 retries starting at 1 second.
+func WithDataDogRetry(maxRetries int, backoff time.Duration) DataDogOptFn {
+	return func(config *dataDogConfig) {
+		config.maxRetries = maxRetries
+		config.retryBackoff = backoff
+	}
+}
+
+// WithDataDogHTTPClient sets the HTTP client used to send the logs. Defaults to a client with 10 seconds timeout.
+func WithDataDogHTTPClient(client *http.Client) DataDogOptFn {
+	return func(config *dataDogConfig) {
+		config.client = client
+	}
+}
+
+// WithDataDog forwards the logs to the Datadog logs intake, on top of the other sinks.
+// The intakeURL is the base URL of the intake, e.g. https://http-intake.logs.datadoghq.com.
+// The logs are buffered, batched, gzipped & sent asynchronously, so call Close on shutdown to not lose them.
+func WithDataDog(apiKey string, intakeURL string, opts ...DataDogOptFn) InitOptFn {
+	return func(config *initConfig) {
+		config.externalSinks = append(config.externalSinks, func(cfg *initConfig) (io.Writer, io.Closer, error) {
+			ddCfg := dataDogConfig{
+				apiKey:        apiKey,
+				intakeURL:     intakeURL,
+				serviceName:   cfg.serviceName,
+				env:           cfg.env,
+				bufferSize:    10000,
+				dropPolicy:    DropNewest,
+				flushInterval: 5 * time.Second,
+				maxRetries:    3,
+				retryBackoff:  time.Second,
+				client:        &http.Client{Timeout: 10 * time.Second},
+			}
+			ddCfg.hostname, _ = os.Hostname()
+			for _, opt := range opts {
+				opt(&ddCfg)
+			}
+
+			w, err := newDataDogWriter(ddCfg)
+			if err != nil {
+				return nil, nil, err
+			}
+			return w, w, nil
+		})
+	}
+}
+
+// dataDogEntry is a single log in the format accepted by the Datadog logs intake.
+// The message is our JSON log line, which Datadog parses into attributes.
+type dataDogEntry struct {
+	Message  string `json:"message"`
+	DDSource string `json:"ddsource"`
+	DDTags   string `json:"ddtags,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// dataDogWriter is an io.Writer that ships every written log line to Datadog asynchronously.
+// Writes never block: if the buffer is full, lines are dropped following the drop policy.
+type dataDogWriter struct {
+	cfg    dataDogConfig
+	url    string
+	ddTags string
+
+	buffer  chan []byte
+	flushCh chan chan struct{}
+	closeCh chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	closed  atomic.Bool
+
+	// dropped is the number of log lines dropped because the buffer was full or the intake rejected them.
+	dropped atomic.Int64
+}
+
+func newDataDogWriter(cfg dataDogConfig) (*dataDogWriter, error) {
+	if cfg.apiKey == "" {
+		return nil, fmt.Errorf("log: datadog api key is empty")
+	}
+	url := strings.TrimSuffix(cfg.intakeURL, "/")
+	if url == "" {
+		return nil, fmt.Errorf("log: datadog intake url is empty")
+	}
+	if !strings.HasSuffix(url, dataDogIntakePath) {
+		url += dataDogIntakePath
+	}
+	if cfg.bufferSize <= 0 {
+		cfg.bufferSize = 1
+	}
+	if cfg.flushInterval <= 0 {
+		cfg.flushInterval = 5 * time.Second
+	}
+
+	tags := append([]string{}, cfg.tags...)
+	if cfg.env != "" {
+		tags = append(tags, "env:"+cfg.env)
+	}
+
+	w := &dataDogWriter{
+		cfg:     cfg,
+		url:     url,
+		ddTags:  strings.Join(tags, ","),
+		buffer:  make(chan []byte, cfg.bufferSize),
+		flushCh: make(chan chan struct{}),
+		closeCh: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+
+	return w, nil
+}
+
+func (w *dataDogWriter) Write(p []byte) (int, error) {
+	if w.closed.Load() {
+		return 0, os.ErrClosed
+	}
+
+	// Copy, the caller reuses p.
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	select {
+	case w.buffer <- line:
+		return len(p), nil
+	default:
+	}
+
+	if w.cfg.dropPolicy == DropOldest {
+		// Make room by dropping the oldest line, then retry once.
+		select {
+		case <-w.buffer:
+			w.dropped.Add(1)
+		default:
+		}
+		select {
+		case w.buffer <- line:
+			return len(p), nil
+		default:
+		}
+	}
+	w.dropped.Add(1)
+
+	// Report success, logging must not fail because the forwarding is lagging.
+	return len(p), nil
+}
+
+// Dropped returns the number of log lines dropped so far, see DropCounter.
+func (w *dataDogWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Flush sends all the buffered log lines & waits until they're sent or ctx is done.
+func (w *dataDogWriter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case w.flushCh <- flushed:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes the buffered log lines & stops the writer. Writes after Close fail.
+func (w *dataDogWriter) Close() error {
+	return w.CloseContext(context.Background())
+}
+
+// CloseContext is Close that gives up waiting for the pending log lines once ctx is done.
+func (w *dataDogWriter) CloseContext(ctx context.Context) error {
+	w.once.Do(func() {
+		w.closed.Store(true)
+		close(w.closeCh)
+	})
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dataDogBatch is a batch to send, or a flush marker closing flushed once the batches before it are sent.
+type dataDogBatch struct {
+	entries []dataDogEntry
+	flushed chan struct{}
+}
+
+// run batches the buffered lines. The batches are queued for another goroutine sending them, so the buffer keeps
+// being drained while a batch is retried. If too many batches are pending, the new ones are dropped, except the ones
+// drained by Flush & Close: they're queued whatever the number of pending batches, as they're waited for.
+func (w *dataDogWriter) run() {
+	defer close(w.done)
+
+	batches := make(chan dataDogBatch)
+	sent := make(chan struct{})
+	go w.sendBatches(batches, sent)
+
+	ticker := time.NewTicker(w.cfg.flushInterval)
+	defer ticker.Stop()
+
+	var batch []dataDogEntry
+	batchBytes := 0
+	// pending are the batches & flush markers waiting for the sender, in order.
+	var pending []dataDogBatch
+	enqueue := func(keep bool) {
+		if len(batch) > 0 {
+			if keep || len(pending) < dataDogMaxPendingBatches {
+				pending = append(pending, dataDogBatch{entries: batch})
+			} else {
+				w.reportFailure(len(batch), errors.New("too many batches pending"))
+			}
+		}
+		batch = nil
+		batchBytes = 0
+	}
+	add := func(line []byte, keep bool) {
+		if len(line) > dataDogMaxBatchBytes {
+			w.dropped.Add(1)
+			return
+		}
+		if len(batch) >= dataDogMaxBatchEntries || batchBytes+len(line) > dataDogMaxBatchBytes {
+			enqueue(keep)
+		}
+		batch = append(batch, w.entry(line))
+		batchBytes += len(line)
+	}
+	drain := func() {
+		for {
+			select {
+			case line := <-w.buffer:
+				add(line, true)
+			default:
+				enqueue(true)
+				return
+			}
+		}
+	}
+
+	for {
+		// Only hand over the next pending batch if there's one, a nil channel blocks.
+		var next chan<- dataDogBatch
+		var first dataDogBatch
+		if len(pending) > 0 {
+			next, first = batches, pending[0]
+		}
+
+		select {
+		case next <- first:
+			pending = pending[1:]
+		case line := <-w.buffer:
+			add(line, false)
+		case <-ticker.C:
+			enqueue(false)
+		case flushed := <-w.flushCh:
+			drain()
+			pending = append(pending, dataDogBatch{flushed: flushed})
+		case <-w.closeCh:
+			drain()
+			// The sender doesn't wait for the retry backoff once closed.
+			for _, b := range pending {
+				batches <- b
+			}
+			close(batches)
+			<-sent
+			return
+		}
+	}
+}
+
+// sendBatches sends the batches in order until batches is closed, then closes sent.
+func (w *dataDogWriter) sendBatches(batches <-chan dataDogBatch, sent chan<- struct{}) {
+	defer close(sent)
+
+	for batch := range batches {
+		if batch.flushed != nil {
+			close(batch.flushed)
+			continue
+		}
+		w.send(batch.entries)
+	}
+}
+
+func (w *dataDogWriter) entry(line []byte) dataDogEntry {
+	return dataDogEntry{
+		Message:  string(bytes.TrimRight(line, "\n")),
+		DDSource: "go",
+		DDTags:   w.ddTags,
+		Service:  w.cfg.serviceName,
+		Hostname: w.cfg.hostname,
+	}
+}
+
+// send posts the batch, retrying with exponential backoff on network errors, 429 & 5xx.
+func (w *dataDogWriter) send(batch []dataDogEntry) {
+	body, err := gzipJSON(batch)
+	if err != nil {
+		w.reportFailure(len(batch), err)
+		return
+	}
+
+	backoff := w.cfg.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := w.post(body)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt >= w.cfg.maxRetries {
+			w.reportFailure(len(batch), err)
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-w.closeCh:
+			// Still retry on close, but don't wait longer than needed.
+			time.Sleep(min(backoff, 100*time.Millisecond))
+		}
+		backoff *= 2
+	}
+}
+
+func (w *dataDogWriter) post(body []byte) (retryable bool, _ error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("DD-API-KEY", w.cfg.apiKey)
+
+	resp, err := w.cfg.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+	return retryable, fmt.Errorf("datadog intake responded with status %d", resp.StatusCode)
+}
+
+func (w *dataDogWriter) reportFailure(n int, err error) {
+	w.dropped.Add(int64(n))
+	// Can't use the logger here, it'd write back into this writer.
+	fmt.Fprintf(os.Stderr, "log: failed to send %d log lines to datadog: %v\n", n, err)
+}
+
+func gzipJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(v); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,281 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dataDogIntake struct {
+	mu       sync.Mutex
+	entries  []dataDogEntry
+	requests atomic.Int32
+	// failures is the number of requests to fail with 503 before accepting.
+	failures int32
+}
+
+func (i *dataDogIntake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := i.requests.Add(1)
+	if n <= i.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.URL.Path != dataDogIntakePath || r.Header.Get("DD-API-KEY") != "api-key" || r.Header.Get("Content-Encoding") != "gzip" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var entries []dataDogEntry
+	if err := json.NewDecoder(gz).Decode(&entries); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	i.mu.Lock()
+	i.entries = append(i.entries, entries...)
+	i.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (i *dataDogIntake) received() []dataDogEntry {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]dataDogEntry{}, i.entries...)
+}
+
+func TestDataDogWriter_FlushBatchesAndRetries(t *testing.T) {
+	// Given an intake that fails twice before accepting.
+	intake := &dataDogIntake{failures: 2}
+	srv := httptest.NewServer(intake)
+	defer srv.Close()
+
+	w, err := newDataDogWriter(dataDogConfig{
+		apiKey:        "api-key",
+		intakeURL:     srv.URL,
+		serviceName:   "service-name",
+		env:           "test",
+		tags:          []string{"team:core"},
+		bufferSize:    10,
+		flushInterval: time.Hour,
+		maxRetries:    3,
+		retryBackoff:  time.Millisecond,
+		client:        srv.Client(),
+	})
+	require.NoError(t, err)
+
+	// When we write some lines & flush.
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte(`{"message":"hello"}` + "\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Flush(context.Background()))
+
+	// Then they're sent as a single batch after the retries.
+	entries := intake.received()
+	require.Len(t, entries, 3)
+	assert.Equal(t, dataDogEntry{
+		Message:  `{"message":"hello"}`,
+		DDSource: "go",
+		DDTags:   "team:core,env:test",
+		Service:  "service-name",
+	}, entries[0])
+	assert.EqualValues(t, 3, intake.requests.Load())
+	assert.Zero(t, w.Dropped())
+
+	require.NoError(t, w.Close())
+}
+
+func TestDataDogWriter_DropPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		policy   DataDogDropPolicy
+		expected []string
+	}{
+		{name: "drop newest", policy: DropNewest, expected: []string{"1", "2"}},
+		{name: "drop oldest", policy: DropOldest, expected: []string{"2", "3"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given a writer with a full buffer that isn't drained yet.
+			intake := &dataDogIntake{}
+			srv := httptest.NewServer(intake)
+			defer srv.Close()
+
+			w := &dataDogWriter{
+				cfg:     dataDogConfig{apiKey: "api-key", flushInterval: time.Hour, dropPolicy: tt.policy, client: srv.Client()},
+				url:     srv.URL + dataDogIntakePath,
+				buffer:  make(chan []byte, 2),
+				flushCh: make(chan chan struct{}),
+				closeCh: make(chan struct{}),
+				done:    make(chan struct{}),
+			}
+
+			// When we write more lines than the buffer size.
+			for _, line := range []string{"1", "2", "3"} {
+				_, err := w.Write([]byte(line))
+				require.NoError(t, err)
+			}
+			go w.run()
+			require.NoError(t, w.Close())
+
+			// Then a line is dropped following the policy.
+			var messages []string
+			for _, e := range intake.received() {
+				messages = append(messages, e.Message)
+			}
+			assert.Equal(t, tt.expected, messages)
+			assert.EqualValues(t, 1, w.Dropped())
+		})
+	}
+}
+
+func TestNew_WithDataDog(t *testing.T) {
+	// Given a logger forwarding to datadog.
+	intake := &dataDogIntake{}
+	srv := httptest.NewServer(intake)
+	defer srv.Close()
+	l, err := New("service-name", "test",
+		WithWriter(io.Discard),
+		WithDataDog("api-key", srv.URL, WithDataDogFlushInterval(10*time.Millisecond)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close(context.Background()) })
+
+	// When we log.
+	l.Info(context.Background(), Fields{"key": "value"}, "Hello, World!")
+
+	// Then the line is forwarded eventually.
+	assert.Eventually(t, func() bool {
+		entries := intake.received()
+		return len(entries) == 1 && entries[0].Service == "service-name"
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(t, l.Dropped())
+}
+
+func TestDataDogWriter_BuffersWhileRetrying(t *testing.T) {
+	// Given a writer whose first batch failed & is waiting to be retried.
+	intake := &dataDogIntake{failures: 1}
+	srv := httptest.NewServer(intake)
+	defer srv.Close()
+	w, err := newDataDogWriter(dataDogConfig{
+		apiKey:        "api-key",
+		intakeURL:     srv.URL,
+		bufferSize:    1,
+		flushInterval: 10 * time.Millisecond,
+		maxRetries:    3,
+		retryBackoff:  time.Hour,
+		client:        srv.Client(),
+	})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("1"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return intake.requests.Load() == 1 }, time.Second, time.Millisecond)
+
+	// When we write during the retry backoff.
+	_, err = w.Write([]byte("2"))
+	require.NoError(t, err)
+
+	// Then the buffer keeps being drained, so the lines aren't dropped.
+	assert.Eventually(t, func() bool { return len(w.buffer) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, w.Close())
+	var messages []string
+	for _, e := range intake.received() {
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{"1", "2"}, messages)
+	assert.Zero(t, w.Dropped())
+}
+
+func TestDataDogWriter_FlushWhileRetrying(t *testing.T) {
+	// Given a writer whose first batch failed & is waiting to be retried.
+	intake := &dataDogIntake{failures: 1}
+	srv := httptest.NewServer(intake)
+	defer srv.Close()
+	w, err := newDataDogWriter(dataDogConfig{
+		apiKey:        "api-key",
+		intakeURL:     srv.URL,
+		bufferSize:    10,
+		flushInterval: time.Hour,
+		maxRetries:    3,
+		retryBackoff:  time.Hour,
+		client:        srv.Client(),
+	})
+	require.NoError(t, err)
+	flush := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		return w.Flush(ctx)
+	}
+	_, err = w.Write([]byte("0"))
+	require.NoError(t, err)
+	require.ErrorIs(t, flush(), context.DeadlineExceeded)
+
+	// When we flush more batches than can be pending.
+	expected := []string{"0"}
+	for i := 1; i <= dataDogMaxPendingBatches+1; i++ {
+		line := strconv.Itoa(i)
+		expected = append(expected, line)
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		require.ErrorIs(t, flush(), context.DeadlineExceeded)
+	}
+
+	// Then the buffer keeps being drained.
+	_, err = w.Write([]byte("last"))
+	require.NoError(t, err)
+	expected = append(expected, "last")
+	assert.Eventually(t, func() bool { return len(w.buffer) == 0 }, time.Second, time.Millisecond)
+
+	// And no flushed line is dropped, they're all sent once the retry succeeds.
+	require.NoError(t, w.Close())
+	var messages []string
+	for _, e := range intake.received() {
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, expected, messages)
+	assert.Zero(t, w.Dropped())
+}
+
+func TestLogger_FatalFlushesDataDog(t *testing.T) {
+	// Given a logger forwarding to datadog, with lines still buffered.
+	intake := &dataDogIntake{}
+	srv := httptest.NewServer(intake)
+	defer srv.Close()
+	l, err := New("service-name", "test",
+		WithWriter(io.Discard),
+		WithDataDog("api-key", srv.URL, WithDataDogFlushInterval(time.Hour)),
+	)
+	require.NoError(t, err)
+	var exitCode int
+	exit = func(code int) { exitCode = code }
+	t.Cleanup(func() { exit = os.Exit })
+	l.Info(context.Background(), nil, "Buffered")
+
+	// When we log a fatal error.
+	l.Fatal(context.Background(), errors.New("boom"), nil, "Failed to start")
+
+	// Then the buffered & fatal lines are sent before exiting.
+	assert.Equal(t, 1, exitCode)
+	entries := intake.received()
+	require.Len(t, entries, 2)
+	assert.Contains(t, entries[0].Message, `"message":"Buffered"`)
+	assert.Contains(t, entries[1].Message, `"level":"fatal"`)
+	assert.Contains(t, entries[1].Message, `"message":"Failed to start"`)
+}
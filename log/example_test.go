@@ -6,14 +6,16 @@ import (
 	"github.com/pixel8labs/logtrace/log"
 )
 
-func ExampleInfo_WithoutInit() {
+func ExampleInfo_withoutInit() {
 	log.Info(context.Background(), log.Fields{"key": "value"}, "Hello, World!")
 	// Example output: {"level":"info","context":{"key":"value"},"service":"","env":"","time":"2025-02-04T20:57:21+07:00","message":"Hello, World!"}
 	// Can't put the actual output here because the time is dynamic.
 }
 
-func ExampleInit_WithFieldsToScrub() {
-	log.Init("service-name", "development", log.WithFieldsToScrub([]string{"password"}))
+func ExampleInit_withFieldsToScrub() {
+	if err := log.Init("service-name", "development", log.WithFieldsToScrub([]string{"password"})); err != nil {
+		panic(err)
+	}
 	log.Info(context.Background(), log.Fields{
 		"password": "shouldbescrubbed",
 		"username": "name",
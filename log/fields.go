@@ -0,0 +1,63 @@
+package log
+
+import (
+	"context"
+)
+
+type fieldsCtxKey struct{}
+
+// WithFields returns a copy of ctx with the fields bound to it.
+// The bound fields are merged into the context of every line logged with the returned context,
+// so e.g. user_id/tenant_id don't need to be repeated on every call.
+// Fields bound by a parent context are kept, unless overridden.
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsCtxKey{}, mergeFields(FieldsFromContext(ctx), fields))
+}
+
+// FieldsFromContext returns the fields bound to ctx by WithFields.
+func FieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsCtxKey{}).(Fields)
+
+	return fields
+}
+
+// With returns a child logger with the fields bound to it.
+// The bound fields are merged into the context of every line logged by the child.
+func (l *Logger) With(fields Fields) *Logger {
+	child := *l
+	child.fields = mergeFields(l.fields, fields)
+
+	return &child
+}
+
+// With returns a child of the default logger with the fields bound to it, see Logger.With.
+func With(fields Fields) *Logger {
+	return Default().With(fields)
+}
+
+// contextFields returns the fields to log: the ones bound to the logger, overridden by the ones bound to ctx,
+// overridden by the ones given to the call.
+func (l *Logger) contextFields(ctx context.Context, fields Fields) Fields {
+	ctxFields := FieldsFromContext(ctx)
+	if len(l.fields) == 0 && len(ctxFields) == 0 {
+		return fields
+	}
+
+	return mergeFields(mergeFields(l.fields, ctxFields), fields)
+}
+
+// mergeFields returns a new map with the fields of both maps, the ones from override win.
+func mergeFields(base Fields, override Fields) Fields {
+	res := make(Fields, len(base)+len(override))
+	for k, v := range base {
+		res[k] = v
+	}
+	for k, v := range override {
+		res[k] = v
+	}
+
+	return res
+}
@@ -0,0 +1,67 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func lastContext(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	var line struct {
+		Context map[string]any `json:"context"`
+	}
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &line))
+
+	return line.Context
+}
+
+func TestWithFields(t *testing.T) {
+	// Given a context with bound fields, bound again by a nested call.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf), log.WithFieldsToScrub([]string{"token"}))
+	require.NoError(t, err)
+	ctx := log.WithFields(context.Background(), log.Fields{"user_id": "u1", "tenant_id": "t1"})
+	ctx = log.WithFields(ctx, log.Fields{"tenant_id": "t2", "token": "secret"})
+
+	// When we log with the context.
+	l.Info(ctx, log.Fields{"key": "value"}, "Hello, World!")
+
+	// Then the bound fields are merged & scrubbed.
+	assert.Equal(t, map[string]any{
+		"user_id":   "u1",
+		"tenant_id": "t2",
+		"token":     "***scrubbed***",
+		"key":       "value",
+	}, lastContext(t, &buf))
+}
+
+func TestLogger_With(t *testing.T) {
+	// Given a child logger with bound fields.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf))
+	require.NoError(t, err)
+	child := l.With(log.Fields{"component": "billing", "user_id": "from-logger"})
+	ctx := log.WithFields(context.Background(), log.Fields{"user_id": "from-ctx"})
+
+	// When we log with the child, the context & call fields.
+	child.Info(ctx, log.Fields{"key": "value"}, "Hello, World!")
+
+	// Then the call fields win over the context ones, which win over the logger ones.
+	assert.Equal(t, map[string]any{
+		"component": "billing",
+		"user_id":   "from-ctx",
+		"key":       "value",
+	}, lastContext(t, &buf))
+
+	// And the parent logger is left as-is.
+	l.Info(context.Background(), log.Fields{"key": "value"}, "Hello, World!")
+	assert.Equal(t, map[string]any{"key": "value"}, lastContext(t, &buf))
+}
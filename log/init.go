@@ -1,18 +1,31 @@
 package log
 
 import (
-	"io"
+	"context"
+	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
+
+	"github.com/pixel8labs/logtrace/trace"
 )
 
 type initConfig struct {
-	writer io.Writer
-	// externalWriter is to write to external resource, e.g. DataDog.
-	externalWriter io.Writer
+	serviceName string
+	env         string
+	// sinks are where the logs are written to. Defaults to os.Stdout if empty.
+	sinks []sinkOpener
+	// rotation is the rotation config of the file sinks. No rotation if nil.
+	rotation *rotationConfig
+	// externalSinks are to write to external resource, e.g. DataDog.
+	externalSinks []sinkOpener
+	// prettyPrint writes human-readable logs to stdout/stderr. Decided by the env if nil.
+	prettyPrint *bool
+	// levelSpec is the minimum level of the logs, see ParseLevelSpec. Read from LOG_LEVEL if empty.
+	levelSpec string
 	// fieldsToScrub is a list of fields that should be scrubbed from the logs.
 	fieldsToScrub []string
 }
@@ -27,38 +40,85 @@ func WithFieldsToScrub(fields []string) InitOptFn {
 	}
 }
 
-const dir = "/tmp/shared-logs"
-const path = "app.log"
+// replacedCloseTimeout is how long Init waits for the replaced logger to flush its sinks.
+const replacedCloseTimeout = 5 * time.Second
+
+// initLogger is the logger created by the last Init, closed by the next one.
+// The loggers set with SetDefault aren't tracked, as they're owned by the caller.
+var initLogger atomic.Pointer[Logger]
+
+// Init initializes the default logger used by the package-level functions.
+// It returns an error if one of the sinks can't be opened, in which case the previous logger is kept.
+// Otherwise, the logger created by the previous Init, if any, is closed (see Logger.Close), along with its sinks,
+// e.g. its files & its Datadog forwarding. A logger set with SetDefault is left open.
+func Init(serviceName string, env string, opts ...InitOptFn) error {
+	l, err := New(serviceName, env, opts...)
+	if err != nil {
+		return err
+	}
+
+	defaultLogger.Store(l)
+	if prev := initLogger.Swap(l); prev != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), replacedCloseTimeout)
+		defer cancel()
+		if err := prev.Close(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "log: failed to close the replaced logger: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// New creates a logger independent of the default one, e.g. for tests or multi-tenant services.
+// If serviceName or env is empty, the one set on the spans by trace.InitTracer is used, so logs & spans agree.
+// It returns an error if one of the sinks can't be opened.
+func New(serviceName string, env string, opts ...InitOptFn) (*Logger, error) {
+	if serviceName == "" {
+		serviceName = trace.Service().Name
+	}
+	if env == "" {
+		env = trace.Service().Environment
+	}
 
-func Init(serviceName string, env string, opts ...InitOptFn) {
 	cfg := &initConfig{
-		writer:         os.Stdout,
-		externalWriter: nil,
+		serviceName: serviceName,
+		env:         env,
 	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
 
+	levelSpec, err := levelSpecFromEnv()
+	if cfg.levelSpec != "" {
+		levelSpec, err = ParseLevelSpec(cfg.levelSpec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.prettyPrint == nil {
+		enabled := shouldPrettyPrint(env)
+		cfg.prettyPrint = &enabled
+	}
+
 	// Convert fields to scrub to map for faster lookup.
 	fieldsToScrub := map[string]struct{}{}
 	for _, field := range cfg.fieldsToScrub {
 		// Use lowercase to make it case-insensitive.
 		fieldsToScrub[strings.ToLower(field)] = struct{}{}
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		panic(err)
-	}
-	file, err := os.OpenFile(filepath.Join(dir, path), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+
+	w, closers, err := cfg.openSinks()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	l := zerolog.New(file).With().Timestamp().Logger()
-
-	logger = Logger{
-		logger:        l,
+	return &Logger{
+		logger:        zerolog.New(w),
 		serviceName:   serviceName,
 		env:           env,
 		fieldsToScrub: fieldsToScrub,
-	}
+		levels:        newLevels(levelSpec),
+		sinks:         closers,
+	}, nil
 }
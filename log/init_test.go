@@ -0,0 +1,100 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func TestInit_WithMultipleSinks(t *testing.T) {
+	// Given a writer and a file sink.
+	var buf bytes.Buffer
+	path := filepath.Join(t.TempDir(), "nested", "app.log")
+	require.NoError(t, log.Init("service-name", "test", log.WithWriter(&buf), log.WithFile(path, 0600)))
+
+	// When we log.
+	log.Info(context.Background(), nil, "Hello, World!")
+
+	// Then the log should be written to both sinks.
+	assert.Contains(t, buf.String(), `"message":"Hello, World!"`)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, buf.String(), string(content))
+}
+
+func TestInit_WithUnwritableFile(t *testing.T) {
+	// Given a file sink that can't be created because its parent is a file.
+	parent := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(parent, nil, 0600))
+
+	// When we init the logger.
+	err := log.Init("service-name", "test", log.WithFile(filepath.Join(parent, "app.log"), 0600))
+
+	// Then it should return an error instead of panicking.
+	assert.Error(t, err)
+}
+
+func TestNew_ServiceFromTracer(t *testing.T) {
+	// Given the tracer initialized with the service info.
+	_, err := trace.InitTracer(trace.WithServiceName("service-name"), trace.WithEnvironment("test"))
+	require.NoError(t, err)
+
+	// When we create a logger without service name & env.
+	var buf bytes.Buffer
+	l, err := log.New("", "", log.WithWriter(&buf))
+	require.NoError(t, err)
+	l.Info(context.Background(), nil, "Hello, World!")
+
+	// Then the logs use the same ones as the spans.
+	assert.Contains(t, buf.String(), `"service":"service-name"`)
+	assert.Contains(t, buf.String(), `"env":"test"`)
+}
+
+func TestInit_ClosesReplacedLogger(t *testing.T) {
+	// Given a default logger writing to a file.
+	prev := log.Default()
+	t.Cleanup(func() { log.SetDefault(prev) })
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, log.Init("service-name", "test", log.WithFile(path, 0600)))
+	replaced := log.Default()
+
+	// When it's replaced by another Init.
+	var buf bytes.Buffer
+	require.NoError(t, log.Init("service-name", "test", log.WithWriter(&buf)))
+
+	// Then its file is closed, and the logs go to the new logger only.
+	replaced.Info(context.Background(), nil, "after close")
+	log.Info(context.Background(), nil, "Hello, World!")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "after close")
+	assert.Contains(t, buf.String(), `"message":"Hello, World!"`)
+}
+
+func TestInit_KeepsLoggerSetWithSetDefault(t *testing.T) {
+	// Given a default logger writing to a file, set by the caller.
+	prev := log.Default()
+	t.Cleanup(func() { log.SetDefault(prev) })
+	path := filepath.Join(t.TempDir(), "app.log")
+	custom, err := log.New("service-name", "test", log.WithFile(path, 0600))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = custom.Close(context.Background()) })
+	log.SetDefault(custom)
+
+	// When Init replaces it.
+	require.NoError(t, log.Init("service-name", "test", log.WithWriter(&bytes.Buffer{})))
+
+	// Then it's left open, as the caller still owns it.
+	custom.Info(context.Background(), nil, "still open")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "still open")
+}
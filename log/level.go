@@ -0,0 +1,323 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Level is the severity of a log line.
+type Level = zerolog.Level
+
+const (
+	DebugLevel = zerolog.DebugLevel
+	InfoLevel  = zerolog.InfoLevel
+	WarnLevel  = zerolog.WarnLevel
+	ErrorLevel = zerolog.ErrorLevel
+	FatalLevel = zerolog.FatalLevel
+	PanicLevel = zerolog.PanicLevel
+	// Disabled disables the logs, except Fatal & Panic that still exit/panic.
+	Disabled = zerolog.Disabled
+)
+
+// levelEnvVar is the env var read by New (and the default logger) when no level is given.
+// It accepts the same format as ParseLevelSpec.
+const levelEnvVar = "LOG_LEVEL"
+
+// LevelSpec is the minimum level of the logs, with optional overrides per logger name.
+type LevelSpec struct {
+	// Default is the minimum level of the loggers without override.
+	Default Level
+	// Overrides are the minimum levels per logger name (see Logger.Named).
+	// An override also applies to the descendants of the logger, e.g. "payments" applies to "payments.stripe".
+	Overrides map[string]Level
+}
+
+// ParseLevelSpec parses a level spec like "info" or "payments=debug,*=info", where "*" sets the default level.
+// The default level is debug if not given.
+func ParseLevelSpec(s string) (LevelSpec, error) {
+	spec := LevelSpec{Default: DebugLevel}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, levelStr, found := strings.Cut(part, "=")
+		if !found {
+			name, levelStr = "*", name
+		}
+		name = strings.TrimSpace(name)
+		level, err := parseLevel(levelStr)
+		if err != nil {
+			return LevelSpec{}, fmt.Errorf("%w in %q", err, s)
+		}
+
+		if name == "*" {
+			spec.Default = level
+			continue
+		}
+		if spec.Overrides == nil {
+			spec.Overrides = map[string]Level{}
+		}
+		spec.Overrides[name] = level
+	}
+
+	return spec, nil
+}
+
+func parseLevel(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	level, err := zerolog.ParseLevel(strings.ToLower(s))
+	if err != nil || s == "" {
+		return DebugLevel, fmt.Errorf("log: invalid level %q", s)
+	}
+
+	return level, nil
+}
+
+// String formats the spec in the format accepted by ParseLevelSpec.
+func (s LevelSpec) String() string {
+	if len(s.Overrides) == 0 {
+		return s.Default.String()
+	}
+
+	names := make([]string, 0, len(s.Overrides))
+	for name := range s.Overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		parts = append(parts, name+"="+s.Overrides[name].String())
+	}
+
+	return strings.Join(append(parts, "*="+s.Default.String()), ",")
+}
+
+// levelFor returns the minimum level for the logger with the given name.
+// The most specific override wins, e.g. "payments.stripe" before "payments".
+func (s LevelSpec) levelFor(name string) Level {
+	for name != "" {
+		if level, ok := s.Overrides[name]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+
+	return s.Default
+}
+
+func (s LevelSpec) clone() LevelSpec {
+	res := LevelSpec{Default: s.Default}
+	if len(s.Overrides) > 0 {
+		res.Overrides = make(map[string]Level, len(s.Overrides))
+		for name, level := range s.Overrides {
+			res.Overrides[name] = level
+		}
+	}
+
+	return res
+}
+
+// levels holds the level spec of a logger & its children. It can be changed at runtime,
+// permanently or temporarily, in which case it's reverted after a TTL.
+type levels struct {
+	spec atomic.Pointer[LevelSpec]
+
+	// mu guards the pending revert of a temporary change.
+	mu          sync.Mutex
+	revertTimer *time.Timer
+	revertTo    LevelSpec
+	revertAt    time.Time
+}
+
+func newLevels(spec LevelSpec) *levels {
+	lv := &levels{}
+	lv.set(spec)
+
+	return lv
+}
+
+func (lv *levels) get() LevelSpec {
+	return lv.spec.Load().clone()
+}
+
+// set changes the spec permanently, cancelling the pending revert if any.
+func (lv *levels) set(spec LevelSpec) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	lv.cancelRevert()
+	lv.store(spec)
+}
+
+// update changes the spec with fn permanently. If there's a temporary change, it's kept until its revert,
+// and fn also applies to the spec it reverts to.
+func (lv *levels) update(fn func(spec *LevelSpec)) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	spec := lv.get()
+	fn(&spec)
+	if lv.revertTimer != nil {
+		fn(&lv.revertTo)
+	}
+	lv.store(spec)
+}
+
+// setTemporary changes the spec & reverts it after ttl.
+// If there's already a temporary change, the revert goes back to the spec from before the first one.
+func (lv *levels) setTemporary(spec LevelSpec, ttl time.Duration) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	revertTo := lv.get()
+	if lv.revertTimer != nil {
+		revertTo = lv.revertTo
+		lv.cancelRevert()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		lv.mu.Lock()
+		defer lv.mu.Unlock()
+		// Ignore if cancelled or superseded while waiting for the lock.
+		if lv.revertTimer != timer {
+			return
+		}
+		lv.revertTimer = nil
+		lv.store(lv.revertTo)
+	})
+	lv.revertTimer = timer
+	lv.revertTo = revertTo
+	lv.revertAt = time.Now().Add(ttl)
+	lv.store(spec)
+}
+
+// pendingRevert returns when the current temporary change will be reverted, if any.
+func (lv *levels) pendingRevert() (time.Time, bool) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	return lv.revertAt, lv.revertTimer != nil
+}
+
+// cancelRevert cancels the pending revert. Must be called with lv.mu held.
+func (lv *levels) cancelRevert() {
+	if lv.revertTimer == nil {
+		return
+	}
+	lv.revertTimer.Stop()
+	lv.revertTimer = nil
+	lv.revertAt = time.Time{}
+}
+
+func (lv *levels) store(spec LevelSpec) {
+	spec = spec.clone()
+	lv.spec.Store(&spec)
+}
+
+func (lv *levels) enabled(name string, level Level) bool {
+	return level >= lv.spec.Load().levelFor(name)
+}
+
+// levelSpecFromEnv returns the level spec from the LOG_LEVEL env var, or debug if not set.
+func levelSpecFromEnv() (LevelSpec, error) {
+	return ParseLevelSpec(os.Getenv(levelEnvVar))
+}
+
+// WithLevel sets the minimum level of the logs. Defaults to the LOG_LEVEL env var, or debug if not set.
+func WithLevel(level Level) InitOptFn {
+	return func(config *initConfig) {
+		config.levelSpec = level.String()
+	}
+}
+
+// WithLevelSpec sets the minimum level of the logs with per logger name overrides, e.g. "payments=debug,*=info".
+// See ParseLevelSpec for the format.
+func WithLevelSpec(spec string) InitOptFn {
+	return func(config *initConfig) {
+		config.levelSpec = spec
+	}
+}
+
+// Level returns the current level spec of the logger.
+func (l *Logger) Level() LevelSpec {
+	return l.levels.get()
+}
+
+// SetLevel changes the minimum level of the logger at runtime, keeping the per-name overrides.
+// It applies to the logger and all the loggers derived from it.
+// A temporary change (see SetLevelSpecFor) is still reverted after its TTL, to the spec with the new level.
+func (l *Logger) SetLevel(level Level) {
+	l.levels.update(func(spec *LevelSpec) {
+		spec.Default = level
+	})
+}
+
+// SetLevelSpec replaces the level spec of the logger at runtime, see ParseLevelSpec for the format.
+// It applies to the logger and all the loggers derived from it.
+func (l *Logger) SetLevelSpec(spec string) error {
+	parsed, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	l.levels.set(parsed)
+
+	return nil
+}
+
+// SetLevelSpecFor replaces the level spec of the logger for the given duration, then reverts it.
+// Useful to raise the verbosity during an incident without having to remember to lower it back.
+func (l *Logger) SetLevelSpecFor(spec string, ttl time.Duration) error {
+	parsed, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	l.levels.setTemporary(parsed, ttl)
+
+	return nil
+}
+
+// SetLevel changes the minimum level of the default logger at runtime.
+func SetLevel(level Level) {
+	Default().SetLevel(level)
+}
+
+// SetLevelSpec replaces the level spec of the default logger at runtime.
+func SetLevelSpec(spec string) error {
+	return Default().SetLevelSpec(spec)
+}
+
+// Named returns a child logger with the given name, which is logged in the "logger" field
+// & used to find the level override. The name of a child of a named logger is joined with a dot.
+func (l *Logger) Named(name string) *Logger {
+	child := *l
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	child.name = name
+
+	return &child
+}
+
+// Named returns a named child of the default logger, see Logger.Named.
+func Named(name string) *Logger {
+	return Default().Named(name)
+}
+
+func (l *Logger) enabled(level Level) bool {
+	return l.levels.enabled(l.name, level)
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// levelConfig is the JSON representation of the level spec used by the level handler.
+type levelConfig struct {
+	// Level is the default minimum level.
+	Level string `json:"level,omitempty"`
+	// Overrides are the minimum levels per logger name.
+	Overrides map[string]string `json:"overrides,omitempty"`
+	// Spec is the whole config in the ParseLevelSpec format. Takes precedence over Level & Overrides on update.
+	Spec string `json:"spec,omitempty"`
+	// TTL is how long an update lasts before being reverted, e.g. "15m". The update is permanent if empty.
+	TTL string `json:"ttl,omitempty"`
+	// RevertAt is when the current temporary update will be reverted.
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// LevelHandler returns an http.Handler to inspect & change the level of the logger at runtime.
+//
+//   - GET returns the current level config, e.g. {"level":"info","overrides":{"payments":"debug"},"spec":"payments=debug,*=info"}.
+//   - PUT/POST changes it, with either {"spec":"payments=debug,*=info"} or {"level":"info","overrides":{"payments":"debug"}}.
+//     Add "ttl":"15m" to revert the change automatically after the given duration.
+//
+// It doesn't do any authentication, so make sure it's not publicly exposed.
+func (l *Logger) LevelHandler() http.Handler {
+	return levelHandler{logger: func() *Logger { return l }}
+}
+
+// LevelHandler returns an http.Handler to inspect & change the level of the default logger at runtime.
+// See Logger.LevelHandler.
+func LevelHandler() http.Handler {
+	return levelHandler{logger: Default}
+}
+
+type levelHandler struct {
+	logger func() *Logger
+}
+
+func (h levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l := h.logger()
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if err := updateLevel(l, r); err != nil {
+			writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeLevelJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	writeLevelJSON(w, http.StatusOK, currentLevelConfig(l))
+}
+
+func updateLevel(l *Logger, r *http.Request) error {
+	var req levelConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+
+	spec, err := levelSpecFromConfig(l, req)
+	if err != nil {
+		return err
+	}
+
+	if req.TTL == "" {
+		l.levels.set(spec)
+		return nil
+	}
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil || ttl <= 0 {
+		return fmt.Errorf("invalid ttl %q", req.TTL)
+	}
+	l.levels.setTemporary(spec, ttl)
+
+	return nil
+}
+
+func levelSpecFromConfig(l *Logger, req levelConfig) (LevelSpec, error) {
+	if req.Spec != "" {
+		return ParseLevelSpec(req.Spec)
+	}
+	if req.Level == "" && len(req.Overrides) == 0 {
+		return LevelSpec{}, fmt.Errorf("either spec, level or overrides is required")
+	}
+
+	// Keep the current default level if only the overrides are given.
+	spec := LevelSpec{Default: l.Level().Default}
+	if req.Level != "" {
+		level, err := parseLevel(req.Level)
+		if err != nil {
+			return LevelSpec{}, err
+		}
+		spec.Default = level
+	}
+	for name, levelStr := range req.Overrides {
+		level, err := parseLevel(levelStr)
+		if err != nil {
+			return LevelSpec{}, err
+		}
+		if spec.Overrides == nil {
+			spec.Overrides = map[string]Level{}
+		}
+		spec.Overrides[name] = level
+	}
+
+	return spec, nil
+}
+
+func currentLevelConfig(l *Logger) levelConfig {
+	spec := l.Level()
+	res := levelConfig{
+		Level: spec.Default.String(),
+		Spec:  spec.String(),
+	}
+	if len(spec.Overrides) > 0 {
+		res.Overrides = make(map[string]string, len(spec.Overrides))
+		for name, level := range spec.Overrides {
+			res.Overrides[name] = level.String()
+		}
+	}
+	if revertAt, ok := l.levels.pendingRevert(); ok {
+		res.RevertAt = &revertAt
+	}
+
+	return res
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,152 @@
+package log_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func doLevelRequest(t *testing.T, srv *httptest.Server, method string, body string) (int, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var res map[string]any
+	require.NoError(t, json.Unmarshal(raw, &res), string(raw))
+
+	return resp.StatusCode, res
+}
+
+func TestLevelHandler_GetAndPut(t *testing.T) {
+	// Given a level handler of a logger with info level.
+	l, err := log.New("service-name", "test", log.WithWriter(io.Discard), log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+	srv := httptest.NewServer(l.LevelHandler())
+	defer srv.Close()
+
+	// When we get the current level.
+	status, res := doLevelRequest(t, srv, http.MethodGet, "")
+
+	// Then it's returned as JSON.
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "info", res["level"])
+	assert.Equal(t, "info", res["spec"])
+
+	// When we put an override.
+	status, res = doLevelRequest(t, srv, http.MethodPut, `{"overrides":{"payments":"debug"}}`)
+
+	// Then it's applied, keeping the default level.
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "payments=debug,*=info", res["spec"])
+	assert.Equal(t, map[string]any{"payments": "debug"}, res["overrides"])
+	assert.Equal(t, log.DebugLevel, l.Level().Overrides["payments"])
+
+	// When we post a whole spec.
+	status, res = doLevelRequest(t, srv, http.MethodPost, `{"spec":"warn"}`)
+
+	// Then it replaces the config.
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "warn", res["spec"])
+	assert.Nil(t, res["overrides"])
+}
+
+func TestLevelHandler_TemporaryChange(t *testing.T) {
+	// Given a level handler of a logger with info level.
+	l, err := log.New("service-name", "test", log.WithWriter(io.Discard), log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+	srv := httptest.NewServer(l.LevelHandler())
+	defer srv.Close()
+
+	// When we raise the verbosity temporarily.
+	status, res := doLevelRequest(t, srv, http.MethodPut, `{"level":"debug","ttl":"50ms"}`)
+
+	// Then it's applied with the revert time.
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "debug", res["level"])
+	assert.NotEmpty(t, res["revert_at"])
+
+	// And reverted after the TTL.
+	assert.Eventually(t, func() bool {
+		return l.Level().Default == log.InfoLevel
+	}, time.Second, 10*time.Millisecond)
+	_, res = doLevelRequest(t, srv, http.MethodGet, "")
+	assert.Nil(t, res["revert_at"])
+}
+
+func TestLevelHandler_PermanentChangeSurvivesRevert(t *testing.T) {
+	// Given a temporary change.
+	l, err := log.New("service-name", "test", log.WithWriter(io.Discard), log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+	require.NoError(t, l.SetLevelSpecFor("debug", 50*time.Millisecond))
+
+	// When the level is changed permanently before the TTL.
+	l.SetLevel(log.ErrorLevel)
+	time.Sleep(100 * time.Millisecond)
+
+	// Then it's not reverted.
+	assert.Equal(t, log.ErrorLevel, l.Level().Default)
+}
+
+func TestLogger_SetLevelDuringTemporaryChange(t *testing.T) {
+	// Given a temporary per-name override.
+	l, err := log.New("service-name", "test", log.WithWriter(io.Discard), log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+	require.NoError(t, l.SetLevelSpecFor("payments=debug,*=info", 50*time.Millisecond))
+
+	// When the level is changed before the TTL.
+	l.SetLevel(log.WarnLevel)
+
+	// Then it applies right away, on top of the temporary override.
+	assert.Equal(t, log.LevelSpec{
+		Default:   log.WarnLevel,
+		Overrides: map[string]log.Level{"payments": log.DebugLevel},
+	}, l.Level())
+
+	// And the temporary override is still reverted after the TTL, keeping the new level.
+	require.Eventually(t, func() bool {
+		return len(l.Level().Overrides) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, log.LevelSpec{Default: log.WarnLevel}, l.Level())
+}
+
+func TestLevelHandler_Errors(t *testing.T) {
+	l, err := log.New("service-name", "test", log.WithWriter(io.Discard))
+	require.NoError(t, err)
+	srv := httptest.NewServer(l.LevelHandler())
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		expected int
+	}{
+		{name: "invalid json", method: http.MethodPut, body: `{`, expected: http.StatusBadRequest},
+		{name: "empty update", method: http.MethodPut, body: `{}`, expected: http.StatusBadRequest},
+		{name: "invalid level", method: http.MethodPut, body: `{"level":"loud"}`, expected: http.StatusBadRequest},
+		{name: "invalid ttl", method: http.MethodPut, body: `{"level":"info","ttl":"soon"}`, expected: http.StatusBadRequest},
+		{name: "unsupported method", method: http.MethodDelete, expected: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, res := doLevelRequest(t, srv, tt.method, tt.body)
+
+			assert.Equal(t, tt.expected, status)
+			assert.NotEmpty(t, res["error"])
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func TestParseLevelSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected log.LevelSpec
+		wantErr  bool
+	}{
+		{name: "empty defaults to debug", spec: "", expected: log.LevelSpec{Default: log.DebugLevel}},
+		{name: "single level", spec: "WARN", expected: log.LevelSpec{Default: log.WarnLevel}},
+		{
+			name: "overrides",
+			spec: "payments=debug, *=info",
+			expected: log.LevelSpec{
+				Default:   log.InfoLevel,
+				Overrides: map[string]log.Level{"payments": log.DebugLevel},
+			},
+		},
+		{name: "invalid level", spec: "payments=loud", wantErr: true},
+		{name: "missing level", spec: "payments=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := log.ParseLevelSpec(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, spec)
+		})
+	}
+}
+
+func TestLevelSpec_String(t *testing.T) {
+	spec, err := log.ParseLevelSpec("*=info,payments=debug,auth=error")
+	require.NoError(t, err)
+
+	assert.Equal(t, "auth=error,payments=debug,*=info", spec.String())
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	// Given a logger with info level.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf), log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+
+	// When we log debug before & after lowering the level at runtime.
+	l.Debug(context.Background(), nil, "first")
+	l.SetLevel(log.DebugLevel)
+	l.Debug(context.Background(), nil, "second")
+
+	// Then only the second one is written.
+	assert.NotContains(t, buf.String(), "first")
+	assert.Contains(t, buf.String(), "second")
+}
+
+func TestLogger_NamedOverrides(t *testing.T) {
+	// Given a logger with debug enabled for payments only.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf), log.WithLevelSpec("payments=debug,*=info"))
+	require.NoError(t, err)
+
+	// When we log debug through different named loggers.
+	l.Debug(context.Background(), nil, "root")
+	l.Named("auth").Debug(context.Background(), nil, "auth")
+	l.Named("payments").Named("stripe").Debug(context.Background(), nil, "stripe")
+
+	// Then only the payments descendant is written, with its name.
+	assert.NotContains(t, buf.String(), `"message":"root"`)
+	assert.NotContains(t, buf.String(), `"message":"auth"`)
+	assert.Contains(t, buf.String(), `"logger":"payments.stripe"`)
+	assert.Contains(t, buf.String(), `"message":"stripe"`)
+}
+
+func TestNew_LevelFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "error")
+
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf))
+	require.NoError(t, err)
+	l.Warn(context.Background(), nil, "Hello, World!")
+
+	assert.Equal(t, log.ErrorLevel, l.Level().Default)
+	assert.Empty(t, buf.String())
+}
+
+func TestNew_InvalidLevelFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "loud")
+
+	_, err := log.New("service-name", "test")
+
+	assert.Error(t, err)
+}
@@ -2,77 +2,175 @@ package log
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/pixel8labs/logtrace/trace"
 )
 
+// fatalCloseTimeout is how long Fatal waits for the sinks to flush before exiting.
+const fatalCloseTimeout = 5 * time.Second
+
+// exit is os.Exit, replaced in the tests.
+var exit = os.Exit
+
 type Fields map[string]any
 
+// Logger is a structured logger enriching every line with the service, env & trace ids from the context.
+// Create one with New, or use the package-level functions that log through the default logger.
 type Logger struct {
+	// logger has no timestamp in its context, it's added per event so it can be overridden (see SlogHandler).
 	logger        zerolog.Logger
 	serviceName   string
 	env           string
 	fieldsToScrub map[string]struct{}
+	// fields are bound to the logger & merged into the context of every line, see With.
+	fields Fields
+	// name is the name of the logger, see Named.
+	name string
+	// levels is shared with the loggers derived from this one, so changing the level applies to all of them.
+	levels *levels
+	// sinks are the sinks owned by the logger, flushed by Sync & closed by Close.
+	sinks []io.Closer
 }
 
-var (
+// defaultLogger is the logger used by the package-level functions.
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
 	// Initialize default logger, to support older integration.
-	logger = Logger{
-		logger:        zerolog.New(os.Stdout).With().Timestamp().Logger(),
+	// An invalid LOG_LEVEL is ignored here, New reports it.
+	spec, err := levelSpecFromEnv()
+	if err != nil {
+		spec = LevelSpec{Default: DebugLevel}
+	}
+	defaultLogger.Store(&Logger{
+		logger:        zerolog.New(os.Stdout),
 		serviceName:   os.Getenv("SERVICE_NAME"),
 		env:           os.Getenv("APP_ENV"),
 		fieldsToScrub: map[string]struct{}{},
+		levels:        newLevels(spec),
+	})
+}
+
+// Default returns the logger used by the package-level functions.
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault atomically replaces the logger used by the package-level functions.
+func SetDefault(l *Logger) {
+	if l == nil {
+		return
 	}
-)
+	defaultLogger.Store(l)
+}
 
 func Debug(ctx context.Context, context Fields, message string, args ...any) {
-	appendDefaultFields(
+	Default().Debug(ctx, context, message, args...)
+}
+
+func Info(ctx context.Context, context Fields, message string, args ...any) {
+	Default().Info(ctx, context, message, args...)
+}
+
+func Warn(ctx context.Context, context Fields, message string, args ...any) {
+	Default().Warn(ctx, context, message, args...)
+}
+
+func Error(ctx context.Context, err error, context Fields, message string, args ...any) {
+	Default().Error(ctx, err, context, message, args...)
+}
+
+func Fatal(ctx context.Context, err error, context Fields, message string, args ...any) {
+	Default().Fatal(ctx, err, context, message, args...)
+}
+
+func Panic(ctx context.Context, err error, context Fields, message string, args ...any) {
+	Default().Panic(ctx, err, context, message, args...)
+}
+
+func (l *Logger) Debug(ctx context.Context, context Fields, message string, args ...any) {
+	if !l.enabled(DebugLevel) {
+		return
+	}
+	l.appendDefaultFields(
 		ctx,
-		logger.logger.Debug().Interface("context", logger.ScrubFields(context)),
+		l.logger.Debug().Timestamp().Interface("context", l.ScrubFields(l.contextFields(ctx, context))),
 	).Msgf(message, args...)
 }
 
-func Info(ctx context.Context, context Fields, message string, args ...any) {
-	appendDefaultFields(
+func (l *Logger) Info(ctx context.Context, context Fields, message string, args ...any) {
+	if !l.enabled(InfoLevel) {
+		return
+	}
+	l.appendDefaultFields(
 		ctx,
-		logger.logger.Info().Interface("context", logger.ScrubFields(context)),
+		l.logger.Info().Timestamp().Interface("context", l.ScrubFields(l.contextFields(ctx, context))),
 	).Msgf(message, args...)
 }
 
-func Warn(ctx context.Context, context Fields, message string, args ...any) {
-	appendDefaultFields(
+func (l *Logger) Warn(ctx context.Context, context Fields, message string, args ...any) {
+	if !l.enabled(WarnLevel) {
+		return
+	}
+	l.appendDefaultFields(
 		ctx,
-		logger.logger.Warn().Interface("context", logger.ScrubFields(context)),
+		l.logger.Warn().Timestamp().Interface("context", l.ScrubFields(l.contextFields(ctx, context))),
 	).Msgf(message, args...)
 }
 
-func Error(ctx context.Context, err error, context Fields, message string, args ...any) {
-	appendDefaultFields(
+func (l *Logger) Error(ctx context.Context, err error, context Fields, message string, args ...any) {
+	if !l.enabled(ErrorLevel) {
+		return
+	}
+	l.appendDefaultFields(
 		ctx,
-		logger.logger.Error().Stack().Err(err).Interface("context", logger.ScrubFields(context)),
+		l.logger.Error().Timestamp().Stack().Err(err).Interface("context", l.ScrubFields(l.contextFields(ctx, context))),
 	).Msgf(message, args...)
 }
 
-func Fatal(ctx context.Context, err error, context Fields, message string, args ...any) {
-	appendDefaultFields(
+// Fatal logs at fatal level, flushes & closes the sinks of the logger (see Close), then exits with status 1.
+// It waits up to fatalCloseTimeout for the sinks, so the buffered logs (e.g. the Datadog ones) aren't lost.
+func (l *Logger) Fatal(ctx context.Context, err error, context Fields, message string, args ...any) {
+	l.appendDefaultFields(
 		ctx,
-		logger.logger.Fatal().Stack().Err(err).Interface("context", logger.ScrubFields(context)),
+		l.logger.WithLevel(zerolog.FatalLevel).Timestamp().Stack().Err(err).
+			Interface("context", l.ScrubFields(l.contextFields(ctx, context))),
 	).Msgf(message, args...)
+
+	l.closeBeforeExit()
+	exit(1)
 }
 
-func Panic(ctx context.Context, err error, context Fields, message string, args ...any) {
-	appendDefaultFields(
+// closeBeforeExit flushes & closes the sinks, giving up after fatalCloseTimeout.
+func (l *Logger) closeBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), fatalCloseTimeout)
+	defer cancel()
+	if err := l.Close(ctx); err != nil {
+		// Can't use the logger here, its sinks are closed.
+		fmt.Fprintf(os.Stderr, "log: close the logger before exiting: %v\n", err)
+	}
+}
+
+func (l *Logger) Panic(ctx context.Context, err error, context Fields, message string, args ...any) {
+	l.appendDefaultFields(
 		ctx,
-		logger.logger.Panic().Stack().Err(err).Interface("context", logger.ScrubFields(context)),
+		l.logger.Panic().Timestamp().Stack().Err(err).Interface("context", l.ScrubFields(l.contextFields(ctx, context))),
 	).Msgf(message, args...)
 }
 
-func appendDefaultFields(ctx context.Context, event *zerolog.Event) *zerolog.Event {
-	event = event.Str("service", logger.serviceName)
-	event = event.Str("env", logger.env)
+func (l *Logger) appendDefaultFields(ctx context.Context, event *zerolog.Event) *zerolog.Event {
+	event = event.Str("service", l.serviceName)
+	event = event.Str("env", l.env)
+	if l.name != "" {
+		event = event.Str("logger", l.name)
+	}
 	event = appendTraceId(ctx, event)
 
 	return event
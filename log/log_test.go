@@ -0,0 +1,49 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func TestNew_IndependentLoggers(t *testing.T) {
+	// Given two loggers with different configurations.
+	var first, second bytes.Buffer
+	l1, err := log.New("first", "test", log.WithWriter(&first))
+	require.NoError(t, err)
+	l2, err := log.New("second", "test", log.WithWriter(&second), log.WithFieldsToScrub([]string{"password"}))
+	require.NoError(t, err)
+
+	// When we log through both.
+	l1.Info(context.Background(), log.Fields{"password": "secret"}, "Hello, %s!", "first")
+	l2.Info(context.Background(), log.Fields{"password": "secret"}, "Hello, %s!", "second")
+
+	// Then each one writes with its own configuration.
+	assert.Contains(t, first.String(), `"service":"first"`)
+	assert.Contains(t, first.String(), `"password":"secret"`)
+	assert.Contains(t, second.String(), `"service":"second"`)
+	assert.Contains(t, second.String(), `"password":"***scrubbed***"`)
+}
+
+func TestSetDefault(t *testing.T) {
+	// Given a logger set as the default.
+	prev := log.Default()
+	defer log.SetDefault(prev)
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf))
+	require.NoError(t, err)
+	log.SetDefault(l)
+
+	// When we log through the package-level functions.
+	log.Warn(context.Background(), nil, "Hello, World!")
+
+	// Then it's written by the default logger.
+	assert.Same(t, l, log.Default())
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `"service":"service-name"`)
+}
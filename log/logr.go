@@ -0,0 +1,109 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// logrInfoVerbosity is the highest logr verbosity logged at info level, the more verbose ones are logged at debug.
+// OpenTelemetry logs its warnings at V(1), so they're kept at info.
+const logrInfoVerbosity = 1
+
+// logrSink is a logr.LogSink writing through a Logger, e.g. for OpenTelemetry or Kubernetes-style libraries.
+type logrSink struct {
+	// logger is the logger to write to. The default logger at the time of logging is used if nil.
+	logger *Logger
+	// name is the name given by WithName, joined with dots.
+	name string
+	// fields are the key-values given by WithValues.
+	fields Fields
+}
+
+// NewLogr returns a logr.Logger writing through l, with V(0) & V(1) logged at info and more verbose at debug.
+// If l is nil, it writes through the default logger at the time of logging, so it follows SetDefault/Init.
+func NewLogr(l *Logger) logr.Logger {
+	return logr.New(&logrSink{logger: l})
+}
+
+// Logr returns a logr.Logger writing through the default logger, see NewLogr.
+// E.g. trace.InitTracer(trace.WithLogger(log.Logr())) to log the OpenTelemetry errors through this package.
+func Logr() logr.Logger {
+	return NewLogr(nil)
+}
+
+func (s *logrSink) Init(logr.RuntimeInfo) {}
+
+func (s *logrSink) Enabled(level int) bool {
+	return s.getLogger().enabled(logrLevel(level))
+}
+
+func (s *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	l := s.getLogger()
+	fields := mergeFields(s.fields, keyValuesToFields(keysAndValues))
+	if logrLevel(level) == InfoLevel {
+		l.Info(context.Background(), fields, "%s", msg)
+		return
+	}
+	l.Debug(context.Background(), fields, "%s", msg)
+}
+
+func (s *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	s.getLogger().Error(context.Background(), err, mergeFields(s.fields, keyValuesToFields(keysAndValues)), "%s", msg)
+}
+
+func (s *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	child := *s
+	child.fields = mergeFields(s.fields, keyValuesToFields(keysAndValues))
+
+	return &child
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	child := *s
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+	child.name = name
+
+	return &child
+}
+
+func (s *logrSink) getLogger() *Logger {
+	l := s.logger
+	if l == nil {
+		l = Default()
+	}
+	if s.name != "" {
+		l = l.Named(s.name)
+	}
+
+	return l
+}
+
+func logrLevel(verbosity int) Level {
+	if verbosity <= logrInfoVerbosity {
+		return InfoLevel
+	}
+
+	return DebugLevel
+}
+
+// keyValuesToFields converts the logr key-value pairs to fields. A key without value gets a nil value.
+func keyValuesToFields(keysAndValues []any) Fields {
+	fields := make(Fields, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var value any
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields[key] = value
+	}
+
+	return fields
+}
@@ -0,0 +1,49 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func TestNewLogr(t *testing.T) {
+	// Given a logr logger writing through a logger with info level.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf), log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+	logger := log.NewLogr(l).WithName("otel").WithValues("component", "exporter")
+
+	// When we log with different verbosities.
+	logger.V(4).Info("too verbose")
+	logger.V(1).Info("Hello, World!", "key", "value")
+
+	// Then only the info one is written, with the name & values.
+	assert.NotContains(t, buf.String(), "too verbose")
+	assert.Equal(t, map[string]any{"component": "exporter", "key": "value"}, lastContext(t, &buf))
+	assert.Contains(t, buf.String(), `"level":"info"`)
+	assert.Contains(t, buf.String(), `"logger":"otel"`)
+}
+
+func TestInitTracer_WithLogger(t *testing.T) {
+	// Given the tracer initialized with a logr logger writing through a logger.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf))
+	require.NoError(t, err)
+	_, err = trace.InitTracer(trace.WithLogger(log.NewLogr(l)))
+	require.NoError(t, err)
+
+	// When OpenTelemetry reports an error.
+	otel.Handle(errors.New("failed to export spans"))
+
+	// Then it's logged as a structured line.
+	assert.Contains(t, buf.String(), `"level":"error"`)
+	assert.Contains(t, buf.String(), `"error":"failed to export spans"`)
+	assert.Contains(t, buf.String(), `"service":"service-name"`)
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog"
+)
+
+const (
+	colorDarkGray = 90
+	colorCyan     = 36
+
+	// shortIdLength is the length of the trace_id/span_id shown in pretty print.
+	shortIdLength = 8
+	prettyIndent  = "    "
+)
+
+// WithPrettyPrint writes human-readable logs to the stdout/stderr sinks instead of JSON.
+// It shows colored levels, the short trace_id/span_id & the context fields as indented key=value pairs.
+// Other sinks (e.g. files) keep receiving JSON.
+//
+// It's enabled automatically when stdout is a terminal and the env is "local" or "development".
+func WithPrettyPrint() InitOptFn {
+	return func(config *initConfig) {
+		enabled := true
+		config.prettyPrint = &enabled
+	}
+}
+
+// WithJSONOutput always writes JSON logs, even when pretty print would be enabled automatically.
+func WithJSONOutput() InitOptFn {
+	return func(config *initConfig) {
+		enabled := false
+		config.prettyPrint = &enabled
+	}
+}
+
+// shouldPrettyPrint returns whether pretty print should be enabled by default for the given env.
+func shouldPrettyPrint(env string) bool {
+	if env != "local" && env != "development" {
+		return false
+	}
+
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+func newPrettyWriter(out io.Writer, noColor bool) io.Writer {
+	return zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
+		w.Out = out
+		w.NoColor = noColor
+		w.TimeFormat = time.DateTime
+		w.FieldsExclude = []string{"context", "service", "env", "trace_id", "span_id"}
+		w.FormatExtra = func(evt map[string]any, buf *bytes.Buffer) error {
+			writePrettyTrace(buf, evt, noColor)
+			if fields, ok := evt["context"].(map[string]any); ok {
+				writePrettyFields(buf, fields, 1, noColor)
+			}
+			return nil
+		}
+	})
+}
+
+// writePrettyTrace writes the shortened trace_id & span_id, e.g. "trace=4bf92f35 span=00f067aa".
+func writePrettyTrace(buf *bytes.Buffer, evt map[string]any, noColor bool) {
+	for _, field := range []struct{ key, name string }{{"trace_id", "trace"}, {"span_id", "span"}} {
+		id, ok := evt[field.key].(string)
+		if !ok || id == "" {
+			continue
+		}
+		if len(id) > shortIdLength {
+			id = id[:shortIdLength]
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(colorize(field.name+"="+id, colorDarkGray, noColor))
+	}
+}
+
+// writePrettyFields writes the fields as key=value pairs, one per line, indented by their depth.
+// Nested maps are written under their key with a deeper indentation.
+func writePrettyFields(buf *bytes.Buffer, fields map[string]any, depth int, noColor bool) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	indent := strings.Repeat(prettyIndent, depth)
+	for _, k := range keys {
+		buf.WriteByte('\n')
+		buf.WriteString(indent)
+		if nested, ok := fields[k].(map[string]any); ok && len(nested) > 0 {
+			buf.WriteString(colorize(k+":", colorCyan, noColor))
+			writePrettyFields(buf, nested, depth+1, noColor)
+			continue
+		}
+		buf.WriteString(colorize(k+"=", colorCyan, noColor))
+		buf.WriteString(prettyValue(fields[k]))
+	}
+}
+
+func prettyValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+func colorize(s string, color int, noColor bool) string {
+	if noColor {
+		return s
+	}
+
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, s)
+}
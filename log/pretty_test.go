@@ -0,0 +1,27 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyWriter(t *testing.T) {
+	// Given a pretty writer without color.
+	var buf bytes.Buffer
+	w := newPrettyWriter(&buf, true)
+
+	// When we write a JSON log line with trace ids & nested context.
+	_, err := w.Write([]byte(`{"level":"info","service":"svc","env":"local","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736",` +
+		`"span_id":"00f067aa0ba902b7","context":{"user_id":1,"request":{"method":"GET"}},"time":"2025-02-04T20:57:21+07:00",` +
+		`"message":"Hello, World!"}`))
+
+	// Then it's written as human-readable text.
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "INF Hello, World! trace=4bf92f35 span=00f067aa\n"+
+		"    request:\n"+
+		"        method=GET\n"+
+		"    user_id=1\n")
+	assert.NotContains(t, buf.String(), "service")
+}
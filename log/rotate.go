@@ -0,0 +1,401 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// backupTimeFormat is the timestamp format used in the rotated file names.
+// It avoids ":" so the names are valid on every filesystem.
+const backupTimeFormat = "2006-01-02T15-04-05.000000000"
+
+type rotationConfig struct {
+	// maxSize is the size in bytes after which the file is rotated. 0 means no size limit.
+	maxSize int64
+	// interval is the age of the current file after which it is rotated. 0 means no time limit.
+	interval time.Duration
+	// maxAge is the age after which the rotated files are removed. 0 means they're kept forever.
+	maxAge time.Duration
+	// maxBackups is the number of rotated files to keep. 0 means all of them are kept.
+	maxBackups int
+	// compress gzips the rotated files.
+	compress bool
+}
+
+// WithRotation enables rotation of the file sinks added by WithFile.
+// The file is rotated once it grows past maxSizeMB megabytes, the rotated files are removed once they're
+// older than maxAge or there are more than maxBackups of them, and gzipped if compress is true.
+// Zero values disable the respective limit.
+// The file is also reopened on SIGHUP, so it works along with logrotate-style tooling.
+func WithRotation(maxSizeMB int, maxAge time.Duration, maxBackups int, compress bool) InitOptFn {
+	return func(config *initConfig) {
+		if config.rotation == nil {
+			config.rotation = &rotationConfig{}
+		}
+		config.rotation.maxSize = int64(maxSizeMB) * 1024 * 1024
+		config.rotation.maxAge = maxAge
+		config.rotation.maxBackups = maxBackups
+		config.rotation.compress = compress
+	}
+}
+
+// WithRotationInterval rotates the file sinks added by WithFile once the current file is older than interval,
+// regardless of its size. It can be combined with WithRotation.
+func WithRotationInterval(interval time.Duration) InitOptFn {
+	return func(config *initConfig) {
+		if config.rotation == nil {
+			config.rotation = &rotationConfig{}
+		}
+		config.rotation.interval = interval
+	}
+}
+
+// rotatingFile is an io.WriteCloser that rotates the underlying file based on its size and age.
+// It's safe for concurrent use.
+type rotatingFile struct {
+	path string
+	perm os.FileMode
+	cfg  rotationConfig
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	// stale is whether file is closed, after a failed rotation that couldn't reopen it.
+	stale bool
+
+	// millCh triggers the compression & pruning of the rotated files in the background.
+	millCh chan struct{}
+	sighup chan os.Signal
+	wg     sync.WaitGroup
+}
+
+func newRotatingFile(path string, perm os.FileMode, cfg rotationConfig) (*rotatingFile, error) {
+	return newRotatingFileWithClock(path, perm, cfg, time.Now)
+}
+
+func newRotatingFileWithClock(path string, perm os.FileMode, cfg rotationConfig, now func() time.Time) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:   path,
+		perm:   perm,
+		cfg:    cfg,
+		now:    now,
+		millCh: make(chan struct{}, 1),
+		sighup: make(chan os.Signal, 1),
+	}
+	if err := r.open(path); err != nil {
+		return nil, err
+	}
+
+	r.wg.Add(2)
+	go r.mill()
+	go r.reopenOnSignal()
+	signal.Notify(r.sighup, syscall.SIGHUP)
+
+	// Clean up whatever was left by the previous runs.
+	r.triggerMill()
+
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.stale {
+		if err := r.recover(r.path, nil); err != nil {
+			return 0, err
+		}
+	}
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			if r.stale {
+				return 0, err
+			}
+			// The current file was reopened, so keep writing to it until the rotation succeeds.
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	return n, err
+}
+
+// Close closes the current file and waits for the pending compression & pruning to finish.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	if r.file == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	err := r.file.Close()
+	if r.stale {
+		// Already closed by the failed rotation.
+		err = nil
+	}
+	r.file = nil
+	r.mu.Unlock()
+
+	signal.Stop(r.sighup)
+	close(r.sighup)
+	close(r.millCh)
+	r.wg.Wait()
+
+	return err
+}
+
+// Sync commits the current file to disk.
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+
+	return r.file.Sync()
+}
+
+// Reopen closes and reopens the file on the same path, e.g. after it was moved by logrotate.
+// The new file is opened before the current one is closed, so the logs keep being written to it on failure.
+func (r *rotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	current, stale := r.file, r.stale
+	if err := r.open(r.path); err != nil {
+		return err
+	}
+	r.stale = false
+	if stale {
+		// Already closed by the failed rotation.
+		return nil
+	}
+
+	return current.Close()
+}
+
+func (r *rotatingFile) shouldRotate(writeLen int64) bool {
+	// Never rotate an empty file, otherwise a single big write would create empty backups.
+	if r.size == 0 {
+		return false
+	}
+	if r.cfg.maxSize > 0 && r.size+writeLen > r.cfg.maxSize {
+		return true
+	}
+	if r.cfg.interval > 0 && r.now().Sub(r.openedAt) >= r.cfg.interval {
+		return true
+	}
+
+	return false
+}
+
+// open opens the file on path as the current file, in append mode. Must be called with r.mu held.
+// The current file is left as-is on failure.
+func (r *rotatingFile) open(path string) error {
+	file, err := openFile(path, r.perm)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("log: stat %s: %w", path, err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = r.now()
+	if info.Size() > 0 {
+		// Best-effort to keep the age of an existing file, so restarts don't postpone the time-based rotation.
+		r.openedAt = info.ModTime()
+	}
+
+	return nil
+}
+
+// rotate moves the current file to a timestamped backup & opens a new one. Must be called with r.mu held.
+// The file is closed before being moved, as open files can't be renamed on every OS, so on failure it's reopened
+// where it is, in append mode: the logs keep being written to it, and the rotation is retried on the next writes.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return r.recover(r.path, fmt.Errorf("log: close %s: %w", r.path, err))
+	}
+	backup := r.backupName(r.now())
+	if err := os.Rename(r.path, backup); err != nil {
+		return r.recover(r.path, fmt.Errorf("log: rotate %s: %w", r.path, err))
+	}
+	if err := r.open(r.path); err != nil {
+		return r.recover(backup, err)
+	}
+	r.triggerMill()
+
+	return nil
+}
+
+// recover reopens the closed current file from path after a failed rotation, returning the cause of the failure.
+// If it can't be reopened either, the writes fail until it can be. Must be called with r.mu held.
+func (r *rotatingFile) recover(path string, cause error) error {
+	if err := r.open(path); err != nil {
+		r.stale = true
+		return errors.Join(cause, err)
+	}
+	r.stale = false
+
+	return cause
+}
+
+func (r *rotatingFile) triggerMill() {
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+		// A mill is already pending, it'll pick up this rotation too.
+	}
+}
+
+func (r *rotatingFile) mill() {
+	defer r.wg.Done()
+	for range r.millCh {
+		r.compressAndPrune()
+	}
+}
+
+func (r *rotatingFile) reopenOnSignal() {
+	defer r.wg.Done()
+	for range r.sighup {
+		if err := r.Reopen(); err != nil {
+			fmt.Fprintf(os.Stderr, "log: failed to reopen %s on SIGHUP: %v\n", r.path, err)
+		}
+	}
+}
+
+// backupName returns the name of the rotated file, e.g. /var/log/app-2006-01-02T15-04-05.000000000.log.
+func (r *rotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+func (r *rotatingFile) nameParts() (dir string, prefix string, ext string) {
+	dir = filepath.Dir(r.path)
+	base := filepath.Base(r.path)
+	ext = filepath.Ext(base)
+
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+type backupFile struct {
+	path      string
+	timestamp time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (r *rotatingFile) backups() ([]backupFile, error) {
+	dir, prefix, ext := r.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, ".gz")
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(ts, ext))
+		if err != nil {
+			continue
+		}
+		res = append(res, backupFile{path: filepath.Join(dir, name), timestamp: t})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].timestamp.After(res[j].timestamp)
+	})
+
+	return res, nil
+}
+
+func (r *rotatingFile) compressAndPrune() {
+	backups, err := r.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log: failed to list rotated files of %s: %v\n", r.path, err)
+		return
+	}
+
+	for i, b := range backups {
+		expired := r.cfg.maxAge > 0 && r.now().Sub(b.timestamp) > r.cfg.maxAge
+		if (r.cfg.maxBackups > 0 && i >= r.cfg.maxBackups) || expired {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "log: failed to remove %s: %v\n", b.path, err)
+			}
+			continue
+		}
+		if r.cfg.compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := gzipFile(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "log: failed to compress %s: %v\n", b.path, err)
+			}
+		}
+	}
+}
+
+// gzipFile compresses the file into <path>.gz and removes the original.
+func gzipFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
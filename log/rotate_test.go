@@ -0,0 +1,203 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_RotateBySizeAndPrune(t *testing.T) {
+	// Given a rotating file with a tiny max size, keeping 2 compressed backups.
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := newRotatingFile(path, 0600, rotationConfig{maxSize: 10, maxBackups: 2, compress: true})
+	require.NoError(t, err)
+
+	// When we write more than the max size several times.
+	for i := 0; i < 5; i++ {
+		_, err := r.Write([]byte("0123456789\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	// Then only the current file and the 2 newest compressed backups are kept.
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789\n", string(content))
+
+	backups, err := r.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	for _, b := range backups {
+		require.True(t, strings.HasSuffix(b.path, ".log.gz"), b.path)
+		assert.Equal(t, "0123456789\n", readGzip(t, b.path))
+	}
+}
+
+func TestRotatingFile_PruneByAge(t *testing.T) {
+	// Given a rotating file with an old backup.
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	old := filepath.Join(dir, "app-"+time.Now().Add(-48*time.Hour).UTC().Format(backupTimeFormat)+".log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0600))
+
+	// When the rotating file is opened with a max age of a day.
+	r, err := newRotatingFile(path, 0600, rotationConfig{maxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	// Then the old backup is removed.
+	assert.NoFileExists(t, old)
+}
+
+func TestRotatingFile_RotateByInterval(t *testing.T) {
+	// Given a rotating file with an interval & a controllable clock.
+	path := filepath.Join(t.TempDir(), "app.log")
+	var mu sync.Mutex
+	now := time.Now()
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	r, err := newRotatingFileWithClock(path, 0600, rotationConfig{interval: time.Hour}, clock)
+	require.NoError(t, err)
+
+	// When we write before & after the interval passes.
+	_, err = r.Write([]byte("first\n"))
+	require.NoError(t, err)
+	mu.Lock()
+	now = now.Add(2 * time.Hour)
+	mu.Unlock()
+	_, err = r.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	// Then the first write is in the backup.
+	backups, err := r.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	content, err := os.ReadFile(backups[0].path)
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(content))
+}
+
+func TestRotatingFile_RotateError(t *testing.T) {
+	// Given a rotating file whose backup can't be created, as a non-empty directory has its name.
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Now()
+	r, err := newRotatingFileWithClock(path, 0600, rotationConfig{maxSize: 10}, func() time.Time { return now })
+	require.NoError(t, err)
+	backup := r.backupName(now)
+	require.NoError(t, os.MkdirAll(filepath.Join(backup, "taken"), 0755))
+
+	// When the writes trigger the rotation.
+	for _, line := range []string{"0123456789\n", "second\n", "third\n"} {
+		_, err = r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// Then they still go to the current file, which is rotated once possible.
+	require.NoError(t, os.RemoveAll(backup))
+	_, err = r.Write([]byte("fourth\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	content, err := os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789\nsecond\nthird\n", string(content))
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(content))
+}
+
+func TestRotatingFile_ReopenStale(t *testing.T) {
+	// Given a rotating file left closed by a failed rotation, as its directory was replaced by a file.
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "app.log")
+	r, err := newRotatingFile(path, 0600, rotationConfig{maxSize: 10})
+	require.NoError(t, err)
+	_, err = r.Write([]byte("0123456789\n"))
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0600))
+	_, err = r.Write([]byte("lost\n"))
+	require.Error(t, err)
+	require.True(t, r.stale)
+
+	// When it's reopened once the directory is back.
+	require.NoError(t, os.Remove(dir))
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, r.Reopen())
+
+	// Then the writes go to the reopened file, without opening another one.
+	reopened := r.file
+	assert.False(t, r.stale)
+	_, err = r.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Same(t, reopened, r.file)
+	require.NoError(t, r.Close())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(content))
+}
+
+func TestRotatingFile_ConcurrentWrites(t *testing.T) {
+	// Given a rotating file with a small max size.
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := newRotatingFile(path, 0600, rotationConfig{maxSize: 1024})
+	require.NoError(t, err)
+
+	// When many goroutines write at once.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := r.Write([]byte("0123456789\n"))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, r.Close())
+
+	// Then no line is lost or torn.
+	files, err := r.backups()
+	require.NoError(t, err)
+	paths := []string{path}
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	lines := 0
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		require.NoError(t, err)
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			assert.Equal(t, "0123456789", line)
+			lines++
+		}
+	}
+	assert.Equal(t, 2000, lines)
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	return string(content)
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_ReopenOnSIGHUP(t *testing.T) {
+	// Given a rotating file that was moved away, like logrotate does.
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := newRotatingFile(path, 0600, rotationConfig{})
+	require.NoError(t, err)
+	defer r.Close()
+	require.NoError(t, os.Rename(path, path+".1"))
+
+	// When the process receives SIGHUP.
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	// Then the file is recreated on the original path.
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
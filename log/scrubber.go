@@ -122,7 +122,7 @@ func (l Logger) scrubFields(value reflect.Value) reflect.Value {
 				// If the field name is in the l.fieldsToScrub, replace the value with scrubbedField.
 				fieldName := key.String()
 				if _, ok := l.fieldsToScrub[strings.ToLower(fieldName)]; ok {
-					newVal.SetMapIndex(reflect.ValueOf(fieldName), scrubbedFieldVal)
+					newVal.SetMapIndex(key, scrubbedValue(value.Type().Elem()))
 					continue
 				}
 			}
@@ -165,3 +165,21 @@ func (l Logger) scrubFields(value reflect.Value) reflect.Value {
 		return value
 	}
 }
+
+// scrubbedValue returns the "***scrubbed***" value for a map of the given element type,
+// e.g. []string{"***scrubbed***"} for http.Header & url.Values, or the zero value if it can't hold a string.
+func scrubbedValue(t reflect.Type) reflect.Value {
+	scrubbedFieldVal := reflect.ValueOf(scrubbedField)
+	switch {
+	case scrubbedFieldVal.Type().AssignableTo(t):
+		return scrubbedFieldVal
+	case scrubbedFieldVal.Type().ConvertibleTo(t):
+		return scrubbedFieldVal.Convert(t)
+	case t.Kind() == reflect.Slice && scrubbedFieldVal.Type().ConvertibleTo(t.Elem()):
+		res := reflect.MakeSlice(t, 1, 1)
+		res.Index(0).Set(scrubbedFieldVal.Convert(t.Elem()))
+		return res
+	default:
+		return reflect.Zero(t)
+	}
+}
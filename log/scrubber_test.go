@@ -0,0 +1,32 @@
+package log_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func TestScrubFields_MultiValueMaps(t *testing.T) {
+	// Given a logger scrubbing the tokens & passwords.
+	l, err := log.New("service-name", "test", log.WithFieldsToScrub([]string{"x-token", "password"}))
+	require.NoError(t, err)
+
+	// When we scrub headers & query params, along other fields.
+	scrubbed := l.ScrubFields(map[string]any{
+		"headers":  http.Header{"X-Token": {"token"}, "Accept": {"*/*"}},
+		"query":    url.Values{"password": {"secret"}, "page": {"2"}},
+		"password": "secret",
+	})
+
+	// Then the values are scrubbed, keeping the type of the maps.
+	assert.Equal(t, map[string]any{
+		"headers":  http.Header{"X-Token": {"***scrubbed***"}, "Accept": {"*/*"}},
+		"query":    url.Values{"password": {"***scrubbed***"}, "page": {"2"}},
+		"password": "***scrubbed***",
+	}, scrubbed)
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+)
+
+// sinkOpener opens a sink the logs will be written to.
+// It's called by Init, after all the options are applied, so it can read the final config.
+// The returned closer is nil if the sink is not owned by the logger (e.g. os.Stdout or a user-given writer).
+type sinkOpener func(cfg *initConfig) (io.Writer, io.Closer, error)
+
+// WithWriter adds w as a sink the logs will be written to.
+// Can be called multiple times to write to several sinks at once.
+func WithWriter(w io.Writer) InitOptFn {
+	return func(config *initConfig) {
+		config.sinks = append(config.sinks, func(*initConfig) (io.Writer, io.Closer, error) {
+			return w, nil, nil
+		})
+	}
+}
+
+// WithStdout adds os.Stdout as a sink.
+// This is the default sink if no other sink is given.
+func WithStdout() InitOptFn {
+	return WithWriter(os.Stdout)
+}
+
+// WithStderr adds os.Stderr as a sink.
+func WithStderr() InitOptFn {
+	return WithWriter(os.Stderr)
+}
+
+// WithFile adds the file on the given path as a sink.
+// The file (and its parent directory) will be created if it doesn't exist, and the logs are appended to it.
+// See WithRotation to rotate it.
+func WithFile(path string, perm os.FileMode) InitOptFn {
+	return func(config *initConfig) {
+		config.sinks = append(config.sinks, func(cfg *initConfig) (io.Writer, io.Closer, error) {
+			if cfg.rotation != nil {
+				file, err := newRotatingFile(path, perm, *cfg.rotation)
+				if err != nil {
+					return nil, nil, err
+				}
+				return file, file, nil
+			}
+
+			file, err := openFile(path, perm)
+			if err != nil {
+				return nil, nil, err
+			}
+			return file, file, nil
+		})
+	}
+}
+
+func openFile(path string, perm os.FileMode) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("log: create directory for %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
+	if err != nil {
+		return nil, fmt.Errorf("log: open %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// openSinks opens all the configured sinks and combine them into a single writer.
+// It also returns the closers of the sinks owned by the logger.
+// If one of the sinks fails to open, the already opened ones are closed.
+func (cfg *initConfig) openSinks() (io.Writer, []io.Closer, error) {
+	// Copy, appending to cfg.sinks could write into its backing array.
+	sinks := make([]sinkOpener, 0, len(cfg.sinks)+len(cfg.externalSinks)+1)
+	sinks = append(sinks, cfg.sinks...)
+	if len(sinks) == 0 {
+		sinks = append(sinks, func(*initConfig) (io.Writer, io.Closer, error) { return os.Stdout, nil, nil })
+	}
+	sinks = append(sinks, cfg.externalSinks...)
+
+	writers := make([]io.Writer, 0, len(sinks))
+	var closers []io.Closer
+	for _, open := range sinks {
+		w, c, err := open(cfg)
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, err
+		}
+		if *cfg.prettyPrint && (w == os.Stdout || w == os.Stderr) {
+			w = newPrettyWriter(w, false)
+		}
+		writers = append(writers, w)
+		if c != nil {
+			closers = append(closers, c)
+		}
+	}
+	if len(writers) == 1 {
+		return writers[0], closers, nil
+	}
+
+	return zerolog.MultiLevelWriter(writers...), closers, nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		_ = c.Close()
+	}
+}
+
+// DropCounter is implemented by the sinks dropping log lines instead of blocking, e.g. the Datadog one.
+type DropCounter interface {
+	// Dropped returns the number of log lines dropped so far.
+	Dropped() int64
+}
+
+// Dropped returns the number of log lines dropped so far by the sinks of the logger,
+// e.g. because the Datadog buffer was full or the intake rejected them.
+func (l *Logger) Dropped() int64 {
+	var dropped int64
+	for _, sink := range l.sinks {
+		if s, ok := sink.(DropCounter); ok {
+			dropped += s.Dropped()
+		}
+	}
+
+	return dropped
+}
+
+// Sync flushes the sinks of the logger, e.g. sends the logs buffered for Datadog & syncs the files to disk.
+// It gives up once ctx is done.
+func (l *Logger) Sync(ctx context.Context) error {
+	var errs []error
+	for _, sink := range l.sinks {
+		switch s := sink.(type) {
+		case interface{ Flush(context.Context) error }:
+			errs = append(errs, s.Flush(ctx))
+		case interface{ Sync() error }:
+			errs = append(errs, s.Sync())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close flushes & closes the sinks of the logger, e.g. on shutdown. The logs written after Close are lost.
+// As the sinks are shared with the loggers derived from this one (see Named & With), they're closed too.
+// It gives up waiting for the pending logs once ctx is done.
+func (l *Logger) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range l.sinks {
+		if s, ok := sink.(interface{ CloseContext(context.Context) error }); ok {
+			errs = append(errs, s.CloseContext(ctx))
+			continue
+		}
+		errs = append(errs, sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Sync flushes the sinks of the default logger, see Logger.Sync.
+func Sync(ctx context.Context) error {
+	return Default().Sync(ctx)
+}
+
+// Dropped returns the number of log lines dropped by the sinks of the default logger, see Logger.Dropped.
+func Dropped() int64 {
+	return Default().Dropped()
+}
+
+// Close flushes & closes the sinks of the default logger, see Logger.Close.
+func Close(ctx context.Context) error {
+	return Default().Close(ctx)
+}
@@ -0,0 +1,208 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+)
+
+// SlogHandler is a slog.Handler writing through a Logger, so the logs of log/slog users
+// get the same service/env/trace_id enrichment & scrubbing as ours.
+// The attributes are written in the "context" field, with the groups as nested maps.
+type SlogHandler struct {
+	// logger is the logger to write to. The default logger at the time of logging is used if nil.
+	logger *Logger
+	// fields are the attributes added by WithAttrs, with the groups already resolved.
+	fields Fields
+	// groups are the groups opened by WithGroup, the attributes of the records are nested in them.
+	groups []string
+}
+
+// NewSlogHandler returns a slog.Handler writing through l.
+// If l is nil, it writes through the default logger at the time of logging, so it follows SetDefault/Init.
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l}
+}
+
+// SetSlogDefault makes the log/slog default logger (and so the standard library log package) write
+// through the default logger of this package.
+func SetSlogDefault() {
+	slog.SetDefault(slog.New(NewSlogHandler(nil)))
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.getLogger().enabled(slogLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	l := h.getLogger()
+
+	fields := cloneFields(h.fields)
+	if record.NumAttrs() > 0 {
+		attrs := make([]slog.Attr, 0, record.NumAttrs())
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs = append(attrs, attr)
+			return true
+		})
+		addAttrs(fields, h.groups, attrs)
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	var event *zerolog.Event
+	switch slogLevel(record.Level) {
+	case ErrorLevel:
+		event = l.logger.Error()
+	case WarnLevel:
+		event = l.logger.Warn()
+	case InfoLevel:
+		event = l.logger.Info()
+	default:
+		event = l.logger.Debug()
+	}
+	// A zero time means the time shouldn't be logged.
+	if !record.Time.IsZero() {
+		event = event.Time(zerolog.TimestampFieldName, record.Time)
+	}
+
+	l.appendDefaultFields(
+		ctx,
+		event.Interface("context", l.ScrubFields(l.contextFields(ctx, fields))),
+	).Msg(record.Message)
+
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	child := *h
+	child.fields = cloneFields(h.fields)
+	addAttrs(child.fields, h.groups, attrs)
+
+	return &child
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	child := *h
+	child.groups = append(append([]string{}, h.groups...), name)
+
+	return &child
+}
+
+func (h *SlogHandler) getLogger() *Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+
+	return Default()
+}
+
+// slogLevel maps the slog level to ours, rounding down to the closest one.
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelWarn:
+		return WarnLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
+}
+
+// addAttrs adds the attributes to fields, nested in the groups.
+// The groups are only created if there's at least one non-empty attribute to add.
+func addAttrs(fields Fields, groups []string, attrs []slog.Attr) {
+	target := map[string]any(fields)
+	for _, group := range groups {
+		if !hasNonEmptyAttr(attrs) {
+			return
+		}
+		nested, ok := target[group].(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+			target[group] = nested
+		}
+		target = nested
+	}
+
+	for _, attr := range attrs {
+		addAttr(target, attr)
+	}
+}
+
+func addAttr(target map[string]any, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		attrs := attr.Value.Group()
+		if !hasNonEmptyAttr(attrs) {
+			return
+		}
+		// A group with an empty key is inlined.
+		if attr.Key == "" {
+			for _, a := range attrs {
+				addAttr(target, a)
+			}
+			return
+		}
+		nested, ok := target[attr.Key].(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+			target[attr.Key] = nested
+		}
+		for _, a := range attrs {
+			addAttr(nested, a)
+		}
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			target[attr.Key] = err.Error()
+			return
+		}
+		target[attr.Key] = attr.Value.Any()
+	default:
+		target[attr.Key] = attr.Value.Any()
+	}
+}
+
+func hasNonEmptyAttr(attrs []slog.Attr) bool {
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			continue
+		}
+		if attr.Value.Kind() == slog.KindGroup && !hasNonEmptyAttr(attr.Value.Group()) {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+// cloneFields deep copies the nested maps of fields, so adding attributes doesn't affect the original.
+func cloneFields(fields Fields) Fields {
+	res := make(Fields, len(fields))
+	for k, v := range fields {
+		if nested, ok := v.(map[string]any); ok {
+			v = map[string]any(cloneFields(nested))
+		}
+		res[k] = v
+	}
+
+	return res
+}
@@ -0,0 +1,72 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func TestSlogHandler_Slogtest(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test", log.WithWriter(&buf))
+	require.NoError(t, err)
+
+	err = slogtest.TestHandler(log.NewSlogHandler(l), func() []map[string]any {
+		var results []map[string]any
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(line, &entry))
+
+			// The attributes are in "context", map the rest to the slog keys.
+			result, _ := entry["context"].(map[string]any)
+			if result == nil {
+				result = map[string]any{}
+			}
+			result[slog.MessageKey] = entry["message"]
+			result[slog.LevelKey] = entry["level"]
+			if ts, ok := entry["time"]; ok {
+				result[slog.TimeKey] = ts
+			}
+			results = append(results, result)
+		}
+		return results
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestSlogHandler_EnrichesAndScrubs(t *testing.T) {
+	// Given a slog logger writing through a logger with scrubbing & info level.
+	var buf bytes.Buffer
+	l, err := log.New("service-name", "test",
+		log.WithWriter(&buf),
+		log.WithFieldsToScrub([]string{"password"}),
+		log.WithLevel(log.InfoLevel),
+	)
+	require.NoError(t, err)
+	logger := slog.New(log.NewSlogHandler(l))
+	ctx := log.WithFields(context.Background(), log.Fields{"user_id": "u1"})
+
+	// When we log through slog.
+	logger.DebugContext(ctx, "ignored")
+	logger.WarnContext(ctx, "Hello, World!", slog.Group("auth", slog.String("password", "secret")))
+
+	// Then it's written by the logger.
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "service-name", entry["service"])
+	assert.Equal(t, "Hello, World!", entry["message"])
+	assert.Equal(t, map[string]any{
+		"user_id": "u1",
+		"auth":    map[string]any{"password": "***scrubbed***"},
+	}, entry["context"])
+}
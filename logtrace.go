@@ -0,0 +1,17 @@
+// Package logtrace ties the log & trace packages together.
+package logtrace
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+// Shutdown flushes & closes the tracer and the default logger, e.g. on SIGTERM, so the pending spans
+// and buffered logs aren't lost. The tracer is shut down first, so its errors can still be logged.
+// It gives up once ctx is done, and returns the errors of both joined.
+func Shutdown(ctx context.Context) error {
+	return errors.Join(trace.Shutdown(ctx), log.Close(ctx))
+}
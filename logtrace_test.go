@@ -0,0 +1,85 @@
+package logtrace_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace"
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func TestShutdown_FlushesSpansAndLogs(t *testing.T) {
+	// Given a tracer exporting to a file & a logger forwarding to datadog, both with long flush intervals.
+	prev := log.Default()
+	defer log.SetDefault(prev)
+
+	var received atomic.Int32
+	intake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer intake.Close()
+
+	spansPath := filepath.Join(t.TempDir(), "spans.json")
+	_, err := trace.InitTracer(trace.WithFileExporter(spansPath), trace.WithBatchTimeout(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, log.Init("service-name", "test",
+		log.WithFile(filepath.Join(t.TempDir(), "app.log"), 0600),
+		log.WithDataDog("api-key", intake.URL, log.WithDataDogFlushInterval(time.Hour)),
+	))
+
+	ctx, span := trace.StartSpan(context.Background(), "test", "test-shutdown")
+	log.Info(ctx, nil, "Hello, World!")
+	span.End()
+
+	// When we shut down.
+	err = logtrace.Shutdown(context.Background())
+
+	// Then the pending spans & logs are flushed.
+	require.NoError(t, err)
+	content, err := os.ReadFile(spansPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"test-shutdown"`)
+	assert.EqualValues(t, 1, received.Load())
+}
+
+func TestShutdown_RespectsDeadline(t *testing.T) {
+	// Given a logger forwarding to a datadog intake that hangs.
+	prev := log.Default()
+	defer log.SetDefault(prev)
+
+	release := make(chan struct{})
+	intake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer intake.Close()
+	defer close(release)
+
+	require.NoError(t, log.Init("service-name", "test",
+		log.WithWriter(io.Discard),
+		log.WithDataDog("api-key", intake.URL, log.WithDataDogFlushInterval(time.Hour)),
+	))
+	log.Info(context.Background(), nil, "Hello, World!")
+
+	// When we shut down with a short deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := logtrace.Shutdown(ctx)
+
+	// Then it gives up at the deadline & reports it.
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Less(t, time.Since(start), time.Second)
+}
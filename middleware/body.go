@@ -0,0 +1,105 @@
+package restmiddleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultSkipBodyContentTypes are the content types of the bodies never logged by default, as they are binary.
+var DefaultSkipBodyContentTypes = []string{
+	"multipart/",
+	"application/octet-stream",
+	"application/pdf",
+	"application/zip",
+	"application/gzip",
+	"application/x-protobuf",
+	"application/grpc",
+	"image/",
+	"audio/",
+	"video/",
+	"font/",
+}
+
+// bodyToLog returns the body as logged, decompressed & decoded following its headers, see RegisterBodyDecoder.
+// A truncated body is logged as {"preview":"...","truncated":true,"original_length":123},
+// without original_length if it's unknown.
+func bodyToLog(preview []byte, truncated bool, length int64, header http.Header, limit int64) any {
+	decoded, truncated := decodeBody(preview, truncated, header, limit)
+	if truncated {
+		res := map[string]any{
+			"preview":   decoded,
+			"truncated": true,
+		}
+		if length >= 0 {
+			res["original_length"] = length
+		}
+		return res
+	}
+
+	return decoded
+}
+
+// bodyCapture tees the first limit bytes read from the body, to log them once read, e.g. by the handler.
+// The body isn't buffered: the reader streams it unchanged, without any read ahead.
+type bodyCapture struct {
+	io.ReadCloser
+	limit int64
+
+	mu      sync.Mutex
+	preview bytes.Buffer
+	// n is the number of bytes read from the body.
+	n int64
+	// eof is whether the body was read until the end, so n is its length.
+	eof bool
+}
+
+func newBodyCapture(body io.ReadCloser, limit int64) *bodyCapture {
+	return &bodyCapture{ReadCloser: body, limit: max(limit, 0)}
+}
+
+func (c *bodyCapture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if remaining := c.limit - int64(c.preview.Len()); remaining > 0 {
+		c.preview.Write(p[:min(int64(n), remaining)])
+	}
+	c.n += int64(n)
+	if err == io.EOF {
+		c.eof = true
+	}
+
+	return n, err
+}
+
+// bytesRead returns the number of bytes read from the body.
+func (c *bodyCapture) bytesRead() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.n
+}
+
+// toLog returns the body to log from the bytes read so far, see bodyToLog.
+// A body not read until the end is logged as truncated, unless its Content-Length tells it was read entirely.
+func (c *bodyCapture) toLog(header http.Header, contentLength int64) any {
+	c.mu.Lock()
+	preview := bytes.Clone(c.preview.Bytes())
+	complete := c.eof || (contentLength >= 0 && c.n == contentLength)
+	truncated := c.n > int64(len(preview)) || !complete
+	length := contentLength
+	if c.eof {
+		length = c.n
+	}
+	c.mu.Unlock()
+
+	return bodyToLog(preview, truncated, length, header, c.limit)
+}
+
+// isNoBody returns whether there's no body to read.
+func isNoBody(body io.ReadCloser) bool {
+	return body == nil || body == http.NoBody
+}
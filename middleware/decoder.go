@@ -0,0 +1,273 @@
+package restmiddleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// BodyDecoder decodes a logged body, e.g. into a map so its fields are scrubbed like the other fields.
+// The body is at most the body size limit of the logger.
+type BodyDecoder func(body []byte) (any, error)
+
+// BodyDecompressor decompresses a body with a Content-Encoding, e.g. gzip.
+// Only the first bytes of the returned reader are read, up to the body size limit of the logger.
+type BodyDecompressor func(body []byte) (io.Reader, error)
+
+var (
+	decodersMu sync.RWMutex
+	// decoders are the body decoders by media type, e.g. "application/json".
+	decoders = map[string]BodyDecoder{
+		"application/json":                  JSONBodyDecoder,
+		"application/x-www-form-urlencoded": FormBodyDecoder,
+		"application/xml":                   XMLBodyDecoder,
+		"text/xml":                          XMLBodyDecoder,
+		"application/octet-stream":          Base64BodyDecoder,
+		"application/x-protobuf":            HexBodyDecoder,
+		"application/protobuf":              HexBodyDecoder,
+		"application/grpc":                  HexBodyDecoder,
+	}
+	// decompressors are the body decompressors by content encoding, e.g. "gzip".
+	decompressors = map[string]BodyDecompressor{
+		"gzip":    gzipDecompressor,
+		"x-gzip":  gzipDecompressor,
+		"deflate": deflateDecompressor,
+	}
+)
+
+// RegisterBodyDecoder registers the decoder of the logged bodies with the given media type, e.g.
+// RegisterBodyDecoder("application/vnd.api+xml", XMLBodyDecoder). It replaces the existing one, if any.
+//
+// Without a decoder for their media type, the bodies are decoded with the one of their structured syntax suffix
+// (e.g. "+json" for "application/problem+json"), else as JSON if they are, else as text, else as base64.
+func RegisterBodyDecoder(mediaType string, decoder BodyDecoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// RegisterBodyDecompressor registers the decompressor of the logged bodies with the given Content-Encoding.
+// The "gzip" & "deflate" ones are registered by default.
+func RegisterBodyDecompressor(encoding string, decompressor BodyDecompressor) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decompressors[strings.ToLower(encoding)] = decompressor
+}
+
+// JSONBodyDecoder decodes a JSON body.
+func JSONBodyDecoder(body []byte) (any, error) {
+	var object any
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// FormBodyDecoder decodes a URL-encoded form body into its fields, e.g. {"name":"John","tags":["a","b"]}.
+func FormBodyDecoder(body []byte) (any, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]any, len(values))
+	for key, value := range values {
+		if len(value) == 1 {
+			fields[key] = value[0]
+			continue
+		}
+		fields[key] = value
+	}
+
+	return fields, nil
+}
+
+// XMLBodyDecoder decodes an XML body into nested maps keyed by the element names, e.g.
+// <user id="42"><name>John</name></user> into {"user":{"@id":"42","name":"John"}}.
+// The repeated elements are slices, and the text of the elements with attributes or children is in "#text".
+func XMLBodyDecoder(body []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		current := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: token.Name.Local, attrs: token.Attr}
+			current.children = append(current.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			current.text.Write(token)
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, errors.New("no XML element")
+	}
+
+	return root.value(), nil
+}
+
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     bytes.Buffer
+}
+
+func (n *xmlNode) value() any {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		return text
+	}
+
+	res := make(map[string]any, len(n.attrs)+len(n.children))
+	for _, attr := range n.attrs {
+		res["@"+attr.Name.Local] = attr.Value
+	}
+	for _, child := range n.children {
+		value := child.value()
+		switch existing := res[child.name].(type) {
+		case nil:
+			res[child.name] = value
+		case []any:
+			res[child.name] = append(existing, value)
+		default:
+			res[child.name] = []any{existing, value}
+		}
+	}
+	if text != "" {
+		res["#text"] = text
+	}
+
+	return res
+}
+
+// HexBodyDecoder logs a binary body as its hex preview, e.g. {"hex":"0a0346..."}.
+func HexBodyDecoder(body []byte) (any, error) {
+	return map[string]any{"hex": hex.EncodeToString(body)}, nil
+}
+
+// Base64BodyDecoder logs a binary body as its base64 preview, e.g. {"base64":"iVBORw0..."}.
+func Base64BodyDecoder(body []byte) (any, error) {
+	return map[string]any{"base64": base64.StdEncoding.EncodeToString(body)}, nil
+}
+
+func gzipDecompressor(body []byte) (io.Reader, error) {
+	return gzip.NewReader(bytes.NewReader(body))
+}
+
+// deflateDecompressor handles both the zlib format of the HTTP "deflate" encoding & the raw deflate one,
+// which some clients send instead.
+func deflateDecompressor(body []byte) (io.Reader, error) {
+	if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+		return r, nil
+	}
+
+	return flate.NewReader(bytes.NewReader(body)), nil
+}
+
+// decodeBody returns the body to log, decompressed & decoded following its headers.
+// truncated is whether the body is only the first limit bytes of the original one.
+func decodeBody(body []byte, truncated bool, header http.Header, limit int64) (decoded any, isTruncated bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	if encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))); encoding != "" && encoding != "identity" {
+		decompressor, ok := decompressors[encoding]
+		if !ok {
+			res, _ := Base64BodyDecoder(body)
+			return res, truncated
+		}
+		decompressed, decompressedTruncated, err := decompress(decompressor, body, truncated, limit)
+		if err != nil {
+			res, _ := Base64BodyDecoder(body)
+			return res, truncated
+		}
+		body, truncated = decompressed, decompressedTruncated
+	}
+
+	// A truncated body can't be decoded, only previewed.
+	if truncated {
+		return textOrBase64(body), true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if decoder := findDecoder(mediaType); decoder != nil {
+		if res, err := decoder(body); err == nil {
+			return res, false
+		}
+	}
+	if res, err := JSONBodyDecoder(body); err == nil {
+		return res, false
+	}
+
+	return textOrBase64(body), false
+}
+
+// decompress returns the first limit bytes of the decompressed body.
+func decompress(decompressor BodyDecompressor, body []byte, truncated bool, limit int64) ([]byte, bool, error) {
+	r, err := decompressor(body)
+	if err != nil {
+		return nil, false, err
+	}
+	res, err := io.ReadAll(io.LimitReader(r, limit+1))
+	// The end of a truncated compressed body is missing, so keep what could be decompressed.
+	if err != nil && !(truncated && errors.Is(err, io.ErrUnexpectedEOF)) {
+		return nil, false, fmt.Errorf("decompress body: %w", err)
+	}
+	if int64(len(res)) > limit {
+		return res[:limit], true, nil
+	}
+
+	return res, truncated, nil
+}
+
+func findDecoder(mediaType string) BodyDecoder {
+	mediaType = strings.ToLower(mediaType)
+	if decoder, ok := decoders[mediaType]; ok {
+		return decoder
+	}
+	// Structured syntax suffixes, e.g. application/problem+json.
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if decoder, ok := decoders["application/"+mediaType[i+1:]]; ok {
+			return decoder
+		}
+	}
+
+	return nil
+}
+
+// textOrBase64 returns the body as a string if it's valid UTF-8, else its base64 preview.
+func textOrBase64(body []byte) any {
+	if utf8.Valid(body) {
+		return string(body)
+	}
+	res, _ := Base64BodyDecoder(body)
+
+	return res
+}
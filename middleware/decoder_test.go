@@ -0,0 +1,194 @@
+package restmiddleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, newWriter func(io.Writer) io.WriteCloser, content string) string {
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.String()
+}
+
+func TestDecodeBody(t *testing.T) {
+	gzipWriter := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	zlibWriter := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	flateWriter := func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}
+
+	tests := []struct {
+		name              string
+		header            http.Header
+		body              string
+		limit             int64
+		expected          any
+		expectedTruncated bool
+	}{
+		{
+			name:     "JSON",
+			header:   http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			body:     `{"name":"John"}`,
+			expected: map[string]any{"name": "John"},
+		},
+		{
+			name:     "JSON without Content-Type",
+			body:     `{"name":"John"}`,
+			expected: map[string]any{"name": "John"},
+		},
+		{
+			name:     "Structured syntax suffix",
+			header:   http.Header{"Content-Type": {"application/problem+json"}},
+			body:     `{"title":"Not Found"}`,
+			expected: map[string]any{"title": "Not Found"},
+		},
+		{
+			name:     "Form",
+			header:   http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+			body:     "name=John&password=secret&tags=a&tags=b",
+			expected: map[string]any{"name": "John", "password": "secret", "tags": []string{"a", "b"}},
+		},
+		{
+			name:   "XML",
+			header: http.Header{"Content-Type": {"application/xml"}},
+			body:   `<?xml version="1.0"?><user id="42"><name>John</name><tag>a</tag><tag>b</tag></user>`,
+			expected: map[string]any{
+				"user": map[string]any{"@id": "42", "name": "John", "tag": []any{"a", "b"}},
+			},
+		},
+		{
+			name:     "Invalid XML",
+			header:   http.Header{"Content-Type": {"text/xml"}},
+			body:     "<user>",
+			expected: "<user>",
+		},
+		{
+			name:     "Text",
+			header:   http.Header{"Content-Type": {"text/plain"}},
+			body:     "Hello",
+			expected: "Hello",
+		},
+		{
+			name:     "Protobuf",
+			header:   http.Header{"Content-Type": {"application/x-protobuf"}},
+			body:     "\x0a\x04John",
+			expected: map[string]any{"hex": "0a044a6f686e"},
+		},
+		{
+			name:     "Octet stream",
+			header:   http.Header{"Content-Type": {"application/octet-stream"}},
+			body:     "\x89PNG",
+			expected: map[string]any{"base64": "iVBORw=="},
+		},
+		{
+			name:     "Binary without Content-Type",
+			body:     "\xff\xfe",
+			expected: map[string]any{"base64": "//4="},
+		},
+		{
+			name: "Gzip",
+			header: http.Header{
+				"Content-Type":     {"application/x-www-form-urlencoded"},
+				"Content-Encoding": {"gzip"},
+			},
+			body:     compress(t, gzipWriter, "name=John"),
+			expected: map[string]any{"name": "John"},
+		},
+		{
+			name:     "Deflate",
+			header:   http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"deflate"}},
+			body:     compress(t, zlibWriter, `{"name":"John"}`),
+			expected: map[string]any{"name": "John"},
+		},
+		{
+			name:     "Raw deflate",
+			header:   http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"deflate"}},
+			body:     compress(t, flateWriter, `{"name":"John"}`),
+			expected: map[string]any{"name": "John"},
+		},
+		{
+			name:              "Gzip larger than the limit once decompressed",
+			header:            http.Header{"Content-Type": {"text/plain"}, "Content-Encoding": {"gzip"}},
+			body:              compress(t, gzipWriter, strings.Repeat("a", 100)),
+			limit:             10,
+			expected:          strings.Repeat("a", 10),
+			expectedTruncated: true,
+		},
+		{
+			name:     "Invalid gzip",
+			header:   http.Header{"Content-Encoding": {"gzip"}},
+			body:     "\x00\x01",
+			expected: map[string]any{"base64": "AAE="},
+		},
+		{
+			name:     "Unknown encoding",
+			header:   http.Header{"Content-Encoding": {"br"}},
+			body:     "\x00\x01",
+			expected: map[string]any{"base64": "AAE="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			if limit == 0 {
+				limit = MaxBodySize
+			}
+
+			// When the body is decoded.
+			decoded, truncated := decodeBody([]byte(tt.body), false, tt.header, limit)
+
+			// Then it's decoded following its headers.
+			assert.Equal(t, tt.expected, decoded)
+			assert.Equal(t, tt.expectedTruncated, truncated)
+		})
+	}
+}
+
+func TestDecodeBody_TruncatedGzip(t *testing.T) {
+	// Given the first bytes of a gzip body.
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(strings.Repeat("hello ", 100)))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	preview := buf.Bytes()
+
+	// When it's decoded as truncated.
+	decoded, truncated := decodeBody(preview, true, http.Header{"Content-Encoding": {"gzip"}}, MaxBodySize)
+
+	// Then what could be decompressed is previewed.
+	assert.True(t, truncated)
+	assert.Equal(t, strings.Repeat("hello ", 100), decoded)
+}
+
+func TestRegisterBodyDecoder(t *testing.T) {
+	// Given a decoder registered for a vendor media type.
+	RegisterBodyDecoder("application/vnd.example", XMLBodyDecoder)
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		delete(decoders, "application/vnd.example")
+	})
+
+	// When a body of this media type is decoded.
+	decoded, _ := decodeBody([]byte("<name>John</name>"), false,
+		http.Header{"Content-Type": {"application/vnd.example"}}, MaxBodySize)
+
+	// Then it's decoded with the registered decoder.
+	assert.Equal(t, map[string]any{"name": "John"}, decoded)
+}
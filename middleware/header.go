@@ -0,0 +1,141 @@
+package restmiddleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const redactedHeader = "***redacted***"
+
+// DefaultRedactedHeaders are the headers carrying credentials, redacted from the logs by default.
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Csrf-Token",
+}
+
+// defaultHeaderPolicy logs all the headers, with the DefaultRedactedHeaders redacted.
+var defaultHeaderPolicy = newHeaderPolicy(nil, nil, nil)
+
+// headerPolicy filters & redacts the headers before they are logged.
+// The logger scrubs the remaining ones like the other fields, see log.WithFieldsToScrub.
+type headerPolicy struct {
+	// allow are the only headers logged, all of them if empty.
+	allow map[string]struct{}
+	// deny are the headers never logged.
+	deny map[string]struct{}
+	// redact are the headers logged with their value redacted, but the exp & sub claims of the JWTs.
+	redact map[string]struct{}
+}
+
+func newHeaderPolicy(allowlist, denylist, redacted []string) headerPolicy {
+	if redacted == nil {
+		redacted = DefaultRedactedHeaders
+	}
+
+	return headerPolicy{
+		allow:  headerSet(allowlist),
+		deny:   headerSet(denylist),
+		redact: headerSet(redacted),
+	}
+}
+
+func headerSet(headers []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(headers))
+	for _, header := range headers {
+		res[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	return res
+}
+
+// apply returns a copy of the headers to log.
+func (p headerPolicy) apply(header http.Header) http.Header {
+	res := make(http.Header, len(header))
+	for key, values := range header {
+		canonicalKey := http.CanonicalHeaderKey(key)
+		if _, ok := p.deny[canonicalKey]; ok {
+			continue
+		}
+		if _, ok := p.allow[canonicalKey]; len(p.allow) > 0 && !ok {
+			continue
+		}
+		if _, ok := p.redact[canonicalKey]; ok {
+			redacted := make([]string, len(values))
+			for i, value := range values {
+				redacted[i] = redactHeaderValue(value)
+			}
+			res[key] = redacted
+			continue
+		}
+		res[key] = values
+	}
+
+	return res
+}
+
+// redactHeaderValue redacts the credentials of the value. A JWT, e.g. "Bearer <JWT>", is only partially masked:
+// its header & signature are redacted, and only the exp & sub claims of its payload are kept,
+// e.g. `Bearer ***.{"exp":1700000000,"sub":"user-42"}.***`, to help debugging the expired/wrong tokens.
+func redactHeaderValue(value string) string {
+	scheme, token, found := strings.Cut(value, " ")
+	if !found {
+		scheme, token = "", value
+	}
+	claims, ok := jwtClaims(token)
+	if !ok {
+		return redactedHeader
+	}
+
+	masked := "***." + claims + ".***"
+	if scheme != "" {
+		masked = scheme + " " + masked
+	}
+
+	return masked
+}
+
+// jwtClaims returns the JSON of the exp & sub claims of the token, ok is false if it isn't a JWT.
+func jwtClaims(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg == "" {
+		return "", false
+	}
+	var payload map[string]any
+	if err := decodeJWTPart(parts[1], &payload); err != nil {
+		return "", false
+	}
+
+	kept := map[string]any{}
+	for _, claim := range []string{"exp", "sub"} {
+		if value, ok := payload[claim]; ok {
+			kept[claim] = value
+		}
+	}
+	// A map of JSON values can't fail to marshal.
+	claims, _ := json.Marshal(kept)
+
+	return string(claims), true
+}
+
+func decodeJWTPart(part string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
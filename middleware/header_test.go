@@ -0,0 +1,89 @@
+package restmiddleware
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newJWT(payload string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(payload)) + "." + encode([]byte("signature"))
+}
+
+func TestHeaderPolicy(t *testing.T) {
+	header := http.Header{
+		"Authorization": {"Bearer " + newJWT(`{"sub":"user-42","exp":1700000000,"email":"john@example.com"}`)},
+		"Cookie":        {"session=abc"},
+		"X-Api-Key":     {"key"},
+		"X-Trace":       {"trace"},
+		"Content-Type":  {"application/json"},
+	}
+
+	tests := []struct {
+		name     string
+		policy   headerPolicy
+		expected http.Header
+	}{
+		{
+			name:   "Default",
+			policy: newHeaderPolicy(nil, nil, nil),
+			expected: http.Header{
+				"Authorization": {`Bearer ***.{"exp":1700000000,"sub":"user-42"}.***`},
+				"Cookie":        {"***redacted***"},
+				"X-Api-Key":     {"***redacted***"},
+				"X-Trace":       {"trace"},
+				"Content-Type":  {"application/json"},
+			},
+		},
+		{
+			name:   "Allowlist",
+			policy: newHeaderPolicy([]string{"content-type", "authorization"}, nil, nil),
+			expected: http.Header{
+				"Authorization": {`Bearer ***.{"exp":1700000000,"sub":"user-42"}.***`},
+				"Content-Type":  {"application/json"},
+			},
+		},
+		{
+			name:   "Denylist",
+			policy: newHeaderPolicy(nil, []string{"x-trace", "Authorization"}, nil),
+			expected: http.Header{
+				"Cookie":       {"***redacted***"},
+				"X-Api-Key":    {"***redacted***"},
+				"Content-Type": {"application/json"},
+			},
+		},
+		{
+			name:     "No redaction",
+			policy:   newHeaderPolicy([]string{"Cookie"}, nil, []string{}),
+			expected: http.Header{"Cookie": {"session=abc"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.apply(header))
+		})
+	}
+}
+
+func TestRedactHeaderValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Bearer JWT", value: "Bearer " + newJWT(`{"sub":"user-42","exp":1700000000}`), expected: `Bearer ***.{"exp":1700000000,"sub":"user-42"}.***`},
+		{name: "Raw JWT", value: newJWT(`{"sub":"user-42","role":"admin"}`), expected: `***.{"sub":"user-42"}.***`},
+		{name: "JWT without exp & sub", value: newJWT(`{"role":"admin"}`), expected: `***.{}.***`},
+		{name: "Opaque token", value: "Bearer abc.def.ghi", expected: "***redacted***"},
+		{name: "Basic auth", value: "Basic dXNlcjpwYXNzd29yZA==", expected: "***redacted***"},
+		{name: "Empty", value: "", expected: "***redacted***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redactHeaderValue(tt.value))
+		})
+	}
+}
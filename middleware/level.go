@@ -0,0 +1,25 @@
+package restmiddleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pixel8labs/logtrace/log"
+)
+
+// LevelRouter is implemented by *echo.Echo & *echo.Group.
+type LevelRouter interface {
+	Match(methods []string, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) []*echo.Route
+}
+
+// RegisterLevelHandler registers log.LevelHandler on the given path, to inspect (GET) & change (PUT/POST)
+// the level of the default logger at runtime. The handler doesn't do any authentication,
+// so protect it with the given middleware or register it on a private group/port.
+func RegisterLevelHandler(r LevelRouter, path string, middleware ...echo.MiddlewareFunc) {
+	r.Match(
+		[]string{http.MethodGet, http.MethodPut, http.MethodPost},
+		path,
+		echo.WrapHandler(log.LevelHandler()),
+		middleware...,
+	)
+}
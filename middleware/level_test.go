@@ -0,0 +1,39 @@
+package restmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixel8labs/logtrace/log"
+)
+
+func TestRegisterLevelHandler(t *testing.T) {
+	// Given an echo server with the level handler registered.
+	prev := log.Default()
+	defer log.SetDefault(prev)
+	l, err := log.New("service-name", "test", log.WithLevel(log.InfoLevel))
+	require.NoError(t, err)
+	log.SetDefault(l)
+
+	e := echo.New()
+	RegisterLevelHandler(e, "/admin/log-level")
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	// When we change the level through it.
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/admin/log-level", strings.NewReader(`{"level":"warn"}`))
+	require.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Then the default logger's level is changed.
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, log.WarnLevel, log.Default().Level().Default)
+}
@@ -1,101 +1,317 @@
 package restmiddleware
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+
 	"github.com/pixel8labs/logtrace/log"
-	"io"
-	"net/http"
 )
 
+// MaxBodySize is the default maximum size of the logged bodies, see LoggerConfig.MaxBodySize.
 const MaxBodySize = 16 * 1024 // 16 KB
 
-type customContext struct {
-	echo.Context
+// LoggerConfig configures the logger middleware, see LoggerWithConfig.
+type LoggerConfig struct {
+	// Skipper skips the requests not logged at all. None is skipped by default.
+	Skipper echomiddleware.Skipper
+	// SkipPaths are the paths not logged at all, e.g. "/healthcheck".
+	// Matched against both the route (e.g. "/users/:id") and the request path.
+	SkipPaths []string
+	// HeaderAllowlist are the only request & response headers logged, all of them if empty.
+	HeaderAllowlist []string
+	// HeaderDenylist are the request & response headers never logged.
+	HeaderDenylist []string
+	// RedactHeaders are the headers logged with their value redacted. Defaults to DefaultRedactedHeaders.
+	// The JWTs are partially masked, keeping their exp & sub claims.
+	// Set it to an empty slice to log all the values as-is.
+	RedactHeaders []string
+	// MaxBodySize is the maximum size of the logged request & response bodies. Defaults to MaxBodySize.
+	// Set it to a negative value to never log the bodies.
+	MaxBodySize int64
+	// BodyContentTypes are the only content types of the logged bodies, e.g. "application/json" or "text/",
+	// matched as prefixes of the Content-Type header. All of them are logged if empty.
+	BodyContentTypes []string
+	// SkipBodyContentTypes are the content types of the bodies never logged, matched like BodyContentTypes.
+	// Defaults to DefaultSkipBodyContentTypes, the multipart & binary ones.
+	// Set it to an empty slice to log them all, the binary ones as a hex or base64 preview.
+	SkipBodyContentTypes []string
+	// SampleRate is the ratio (0 to 1) of the successful requests logged, e.g. 0.1 to log 10% of them.
+	// The requests failing with a 4xx/5xx response are always logged, but without their "Incoming request" line
+	// if not sampled. All the requests are logged if 0.
+	SampleRate float64
+	// HandleError calls the echo error handler with the error returned by the handler, like the HandleError of
+	// echo's RequestLoggerConfig, so the response it writes is logged. As a side effect, the response is committed:
+	// the middlewares before this one can't change it anymore.
+	// By default, the error is only returned, and logged with the status of the *echo.HTTPError, else 500.
+	HandleError bool
 }
 
-// JSON override echo's c.JSON
-func (c customContext) JSON(code int, payload interface{}) error {
-	request := c.Request()
-	err := c.Context.JSON(code, payload)
-
-	// Log outgoing response
-	resCtx := map[string]interface{}{
-		"status":  c.Response().Status,
-		"headers": c.Response().Header(),
-		"body":    payload,
-	}
-
-	log.Info(c.Request().Context(), log.Fields{
-		"request":  c.Get("request"),
-		"response": resCtx,
-	}, "Outgoing response: %s %s",
-		request.Method,
-		request.RequestURI,
-	)
-
-	return err
+// Logger is a middleware that logs the incoming request, then exactly one "Outgoing response" line
+// once the response is written, whatever the way: c.JSON, c.String, c.NoContent, c.Stream, a returned error or a panic.
+// See LoggerWithConfig.
+func Logger() echo.MiddlewareFunc {
+	return LoggerWithConfig(LoggerConfig{})
 }
 
-func getObject(rawData []byte) (interface{}, bool) {
-	var object interface{}
-	err := json.Unmarshal(rawData, &object)
-	if err != nil {
-		return nil, false
+// LoggerWithConfig is Logger with the given config.
+// See LoggerConfig for the options, e.g. to skip requests or filter the logged headers & bodies.
+//
+// The "Outgoing response" line is an access log: it carries the latency_ms, bytes_in, bytes_out, status, route,
+// remote_ip, request_id & error fields, on top of the request & the response.
+// It's logged as Info for the 2xx/3xx responses, Warn for the 4xx & Error for the 5xx.
+// When the handler returns an error, the response isn't written yet: the status logged is the one of the
+// *echo.HTTPError, else 500, unless LoggerConfig.HandleError is set.
+//
+// The request & response bodies are logged up to MaxBodySize, the larger ones as a truncated preview, e.g.
+// {"preview":"...","truncated":true,"original_length":123}. The request body isn't buffered: it's captured as the
+// handler streams it, and logged on the "Outgoing response" line only. The multipart & binary bodies aren't logged.
+//
+// The bodies are decompressed & decoded following their Content-Encoding & Content-Type, see RegisterBodyDecoder:
+// the JSON, form & XML fields are scrubbed like the other fields.
+//
+// The credentials headers are redacted, and the other headers are scrubbed like the fields, see LoggerConfig.
+func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = echomiddleware.DefaultSkipper
 	}
-	return object, true
-}
-
-func getRequestBody(req *http.Request) (interface{}, string, bool) {
-	if req.ContentLength > MaxBodySize {
-		return nil, "Skipping body logging: Request body too large", false
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = MaxBodySize
 	}
-
-	var reqBody []byte
-	if req.Body != nil {
-		reqBody, _ = io.ReadAll(req.Body)
+	if config.SkipBodyContentTypes == nil {
+		config.SkipBodyContentTypes = DefaultSkipBodyContentTypes
 	}
-
-	req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
-
-	if len(reqBody) > MaxBodySize {
-		return nil, "Skipping body logging: Request body too large", false
+	headers := newHeaderPolicy(config.HeaderAllowlist, config.HeaderDenylist, config.RedactHeaders)
+	skipPaths := make(map[string]struct{}, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skipPaths[path] = struct{}{}
 	}
 
-	object, ok := getObject(reqBody)
-	return object, string(reqBody), ok
-}
-
-func Logger() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
+		return func(c echo.Context) (err error) {
 			request := c.Request()
+			if config.Skipper(c) {
+				return next(c)
+			}
+			if _, ok := skipPaths[c.Path()]; ok {
+				return next(c)
+			}
+			if _, ok := skipPaths[request.URL.Path]; ok {
+				return next(c)
+			}
+
+			start := time.Now()
 
 			// Log incoming request.
 			reqCtx := map[string]interface{}{
 				"method":  request.Method,
 				"url":     request.RequestURI,
 				"query":   c.QueryParams(),
-				"headers": request.Header,
+				"headers": headers.apply(request.Header),
 			}
 
-			if body, rawString, ok := getRequestBody(request); ok {
-				reqCtx["body"] = body
-			} else {
-				reqCtx["body"] = rawString
+			// The request body is captured as the handler reads it, and logged once it's done.
+			reason, logBody := config.skipBody(request.Header.Get(echo.HeaderContentType))
+			switch {
+			case !logBody:
+				reqCtx["body"] = reason
+			case isNoBody(request.Body):
+				reqCtx["body"] = ""
+				logBody = false
+			}
+			bodyLimit := config.MaxBodySize
+			if !logBody {
+				bodyLimit = 0
+			}
+			bytesIn := newBodyCapture(request.Body, bodyLimit)
+			if request.Body != nil {
+				request.Body = bytesIn
 			}
 
-			log.Info(request.Context(), log.Fields{
-				"request": reqCtx,
-			}, "Incoming request: %s %s",
-				request.Method,
-				request.RequestURI,
-			)
+			sampled := config.SampleRate <= 0 || rand.Float64() < config.SampleRate
+			if sampled {
+				log.Info(request.Context(), log.Fields{
+					"request": reqCtx,
+				}, "Incoming request: %s %s",
+					request.Method,
+					request.RequestURI,
+				)
+			}
 
 			c.Set("request", reqCtx)
 
-			return next(customContext{c})
+			res := c.Response()
+			capture := &responseCapture{ResponseWriter: res.Writer, limit: config.MaxBodySize}
+			res.Writer = capture
+			defer func() {
+				r := recover()
+				if r != nil {
+					err = fmt.Errorf("panic: %v", r)
+				}
+				// The echo error handler doesn't write the committed responses,
+				// so it's fine to return the error to the previous middlewares too.
+				if err != nil && config.HandleError && !res.Committed {
+					c.Error(err)
+				}
+				status := responseStatus(c, err)
+
+				res.Writer = capture.ResponseWriter
+				if logBody {
+					reqCtx["body"] = bytesIn.toLog(request.Header, request.ContentLength)
+				}
+				if sampled || status >= http.StatusBadRequest {
+					logResponse(c, config, reqCtx, headers, capture, completion{
+						status:  status,
+						latency: time.Since(start),
+						bytesIn: bytesIn.bytesRead(),
+						err:     err,
+					})
+				}
+
+				// Let the recover middleware, if any, handle the panic as if we weren't there.
+				if r != nil {
+					panic(r)
+				}
+			}()
+
+			return next(c)
 		}
 	}
 }
+
+// completion is the outcome of a request, logged by logResponse.
+type completion struct {
+	status  int
+	latency time.Duration
+	bytesIn int64
+	err     error
+}
+
+func logResponse(
+	c echo.Context,
+	config LoggerConfig,
+	reqCtx map[string]interface{},
+	headers headerPolicy,
+	capture *responseCapture,
+	done completion,
+) {
+	request := c.Request()
+	res := c.Response()
+
+	resCtx := map[string]interface{}{
+		"status":  done.status,
+		"headers": headers.apply(res.Header()),
+	}
+	if reason, ok := config.skipBody(res.Header().Get(echo.HeaderContentType)); !ok {
+		resCtx["body"] = reason
+	} else {
+		resCtx["body"] = bodyToLog(capture.body.Bytes(), capture.truncated, res.Size, res.Header(), config.MaxBodySize)
+	}
+
+	requestID := res.Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = request.Header.Get(echo.HeaderXRequestID)
+	}
+
+	fields := log.Fields{
+		"request":    reqCtx,
+		"response":   resCtx,
+		"latency_ms": float64(done.latency) / float64(time.Millisecond),
+		"bytes_in":   done.bytesIn,
+		"bytes_out":  res.Size,
+		"status":     done.status,
+		"route":      c.Path(),
+		"remote_ip":  c.RealIP(),
+	}
+	if requestID != "" {
+		fields["request_id"] = requestID
+	}
+	if done.err != nil {
+		fields["error"] = done.err.Error()
+	}
+
+	ctx := request.Context()
+	switch {
+	case done.status >= http.StatusInternalServerError:
+		log.Error(ctx, done.err, fields, "Outgoing response: %s %s", request.Method, request.RequestURI)
+	case done.status >= http.StatusBadRequest:
+		log.Warn(ctx, fields, "Outgoing response: %s %s", request.Method, request.RequestURI)
+	default:
+		log.Info(ctx, fields, "Outgoing response: %s %s", request.Method, request.RequestURI)
+	}
+}
+
+// skipBody returns whether the body of the given content type is logged, and why if not.
+func (config LoggerConfig) skipBody(contentType string) (string, bool) {
+	if config.MaxBodySize < 0 {
+		return "Skipping body logging: Disabled", false
+	}
+	if matchContentType(contentType, config.SkipBodyContentTypes) {
+		return fmt.Sprintf("Skipping body logging: Content-Type %q not logged", contentType), false
+	}
+	if len(config.BodyContentTypes) > 0 && !matchContentType(contentType, config.BodyContentTypes) {
+		return fmt.Sprintf("Skipping body logging: Content-Type %q not logged", contentType), false
+	}
+
+	return "", true
+}
+
+// matchContentType returns whether the content type starts with one of the given ones, case-insensitively.
+func matchContentType(contentType string, contentTypes []string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, ct := range contentTypes {
+		if strings.HasPrefix(contentType, strings.ToLower(ct)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// responseCapture copies the first limit bytes written to the response.
+type responseCapture struct {
+	http.ResponseWriter
+	limit     int64
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *responseCapture) Write(b []byte) (int, error) {
+	if !w.truncated {
+		if remaining := w.limit - int64(w.body.Len()); int64(len(b)) > remaining {
+			w.truncated = true
+			w.body.Write(b[:max(remaining, 0)])
+		} else {
+			w.body.Write(b)
+		}
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, for c.Stream & the server-sent events.
+func (w *responseCapture) Flush() {
+	// Same as echo.Response.Flush.
+	if err := http.NewResponseController(w.ResponseWriter).Flush(); errors.Is(err, http.ErrNotSupported) {
+		panic(errors.New("response writer flushing is not supported"))
+	}
+}
+
+// Hijack implements http.Hijacker, for the websockets.
+func (w *responseCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original writer, for http.ResponseController.
+func (w *responseCapture) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,23 +1,150 @@
 package restmiddleware
 
 import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
 	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
 	"github.com/pixel8labs/logtrace/trace"
 )
 
-// TracingMiddleware is a middleware that creates a new span for each incoming request.
-// TODO: propagate the trace from the request headers.
+// TracerConfig configures the tracing middleware, see TracerWithConfig.
+type TracerConfig struct {
+	// ServiceName is the name of the tracer.
+	ServiceName string
+	// Skipper skips the requests not traced. Their trace is still propagated from the request headers.
+	// None is skipped by default. To sample the traces, see trace.WithSampler & trace.WithSamplingRules.
+	Skipper echomiddleware.Skipper
+}
+
+// Tracer is a middleware that creates a new server span for each incoming request, named "METHOD /route".
+// See TracerWithConfig.
+func Tracer(serviceName string) echo.MiddlewareFunc {
+	return TracerWithConfig(TracerConfig{ServiceName: serviceName})
+}
+
+// TracingMiddleware is the former name of Tracer.
+//
+// Deprecated: Use Tracer or TracerWithConfig instead.
 func TracingMiddleware(serviceName string) echo.MiddlewareFunc {
+	return Tracer(serviceName)
+}
+
+// TracerWithConfig is Tracer with the given config.
+//
+// The trace (and baggage) of the request headers is propagated with the global propagator, see trace.WithPropagators.
+// The span carries the HTTP semantic convention attributes, and is marked as failed on 5xx responses & returned errors.
+func TracerWithConfig(config TracerConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = echomiddleware.DefaultSkipper
+	}
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			req := c.Request()
-			ctx, span := trace.StartSpan(req.Context(), serviceName, c.Path())
+			ctx := trace.ExtractTraceFromHeader(req.Context(), req.Header)
+			if config.Skipper(c) {
+				c.SetRequest(req.WithContext(ctx))
+				return next(c)
+			}
+			ctx, span := trace.StartSpan(ctx, config.ServiceName, spanName(c),
+				oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+				oteltrace.WithAttributes(requestAttributes(c)...),
+			)
 
 			defer span.End()
 
 			c.SetRequest(req.WithContext(ctx))
 
-			return next(c)
+			err := next(c)
+			endSpan(span, c, err)
+
+			return err
 		}
 	}
 }
+
+// spanName returns "METHOD /route", using the route rather than the path to keep a low cardinality.
+func spanName(c echo.Context) string {
+	if c.Path() == "" {
+		return c.Request().Method
+	}
+
+	return c.Request().Method + " " + c.Path()
+}
+
+// requestAttributes returns the semantic convention attributes of the request.
+func requestAttributes(c echo.Context) []attribute.KeyValue {
+	req := c.Request()
+	attrs := []attribute.KeyValue{
+		requestMethod(req.Method),
+		semconv.URLPath(req.URL.Path),
+		semconv.ClientAddress(c.RealIP()),
+	}
+	if c.Path() != "" {
+		attrs = append(attrs, semconv.HTTPRoute(c.Path()))
+	}
+	if userAgent := req.UserAgent(); userAgent != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(userAgent))
+	}
+	host, portStr, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	if host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+	if port, err := strconv.Atoi(portStr); err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+
+	return attrs
+}
+
+// requestMethod returns the http.request.method attribute, "_OTHER" for the non-standard methods.
+func requestMethod(method string) attribute.KeyValue {
+	switch method {
+	case http.MethodConnect, http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPatch, http.MethodPost, http.MethodPut, http.MethodTrace:
+		return semconv.HTTPRequestMethodKey.String(method)
+	default:
+		return semconv.HTTPRequestMethodOther
+	}
+}
+
+// endSpan records the response status of the request, and the error returned by the handler if any.
+func endSpan(span oteltrace.Span, c echo.Context, err error) {
+	status := responseStatus(c, err)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// responseStatus returns the status of the response. When the handler returns an error, the response
+// is only written afterward by the echo error handler, so the status is guessed from the error the same way.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,259 @@
+package restmiddleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+// initTestTracer records the spans in memory until the end of the test.
+func initTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	_, err := trace.InitTracer(trace.WithExporter(exporter))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := trace.InitTracer()
+		require.NoError(t, err)
+	})
+
+	return exporter
+}
+
+func forceFlush(t *testing.T) {
+	t.Helper()
+	tp, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	require.True(t, ok)
+	require.NoError(t, tp.ForceFlush(context.Background()))
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	// Given an echo server with the tracing middleware.
+	exporter := initTestTracer(t)
+	e := echo.New()
+	e.Use(TracingMiddleware("service-name"))
+	var handlerCtx context.Context
+	e.GET("/users/:id", func(c echo.Context) error {
+		handlerCtx = c.Request().Context()
+		return c.NoContent(http.StatusOK)
+	})
+
+	// And an incoming request carrying a trace.
+	parentCtx, parent := trace.StartSpan(context.Background(), "client", "client")
+	parent.End()
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	trace.InjectTraceToHeader(parentCtx, req.Header)
+
+	// When the request is served.
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	forceFlush(t)
+
+	// Then the server span continues the trace of the request.
+	parentTraceId, parentSpanId := trace.TraceIdAndSpanIdFromContext(parentCtx)
+	handlerTraceId, _ := trace.TraceIdAndSpanIdFromContext(handlerCtx)
+	assert.Equal(t, parentTraceId, handlerTraceId)
+
+	var server *tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "GET /users/:id" {
+			server = &span
+		}
+	}
+	require.NotNil(t, server)
+	assert.Equal(t, oteltrace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, parentTraceId, server.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanId, server.Parent.SpanID().String())
+	assert.True(t, server.Parent.IsRemote())
+}
+
+func TestTracingMiddleware_WithoutTrace(t *testing.T) {
+	// Given an echo server with the tracing middleware.
+	initTestTracer(t)
+	e := echo.New()
+	e.Use(TracingMiddleware("service-name"))
+	var handlerCtx context.Context
+	e.POST("/checkout", func(c echo.Context) error {
+		handlerCtx = c.Request().Context()
+		return c.NoContent(http.StatusOK)
+	})
+
+	// When a request without trace is served.
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/checkout", nil))
+
+	// Then a new root trace is started.
+	span := oteltrace.SpanFromContext(handlerCtx)
+	assert.True(t, span.SpanContext().IsValid())
+	roSpan, ok := span.(interface{ Parent() oteltrace.SpanContext })
+	require.True(t, ok)
+	assert.False(t, roSpan.Parent().IsValid())
+}
+
+func TestTracingMiddleware_Attributes(t *testing.T) {
+	// Given an echo server with the tracing middleware.
+	exporter := initTestTracer(t)
+	e := echo.New()
+	e.Use(TracingMiddleware("service-name"))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	// When a request is served.
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com:8080/users/42?debug=true", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	forceFlush(t)
+
+	// Then the span carries the HTTP semantic convention attributes.
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	attrs := map[attribute.Key]any{}
+	for _, kv := range spans[0].Attributes {
+		attrs[kv.Key] = kv.Value.AsInterface()
+	}
+	assert.Equal(t, map[attribute.Key]any{
+		"http.request.method":       "GET",
+		"url.path":                  "/users/42",
+		"http.route":                "/users/:id",
+		"http.response.status_code": int64(http.StatusOK),
+		"user_agent.original":       "test-agent",
+		"client.address":            "203.0.113.7",
+		"server.address":            "api.example.com",
+		"server.port":               int64(8080),
+	}, attrs)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+}
+
+func TestTracingMiddleware_Status(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        echo.HandlerFunc
+		expectedStatus int64
+		expectedCode   codes.Code
+		expectedEvent  bool
+	}{
+		{
+			name:           "4xx response",
+			handler:        func(c echo.Context) error { return c.NoContent(http.StatusNotFound) },
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codes.Unset,
+		},
+		{
+			name:           "5xx response",
+			handler:        func(c echo.Context) error { return c.NoContent(http.StatusBadGateway) },
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   codes.Error,
+		},
+		{
+			name:           "Returned HTTP error",
+			handler:        func(c echo.Context) error { return echo.NewHTTPError(http.StatusConflict, "already exists") },
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codes.Error,
+			expectedEvent:  true,
+		},
+		{
+			name:           "Returned error",
+			handler:        func(c echo.Context) error { return errors.New("db down") },
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   codes.Error,
+			expectedEvent:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given an echo server with the tracing middleware.
+			exporter := initTestTracer(t)
+			e := echo.New()
+			e.Use(TracingMiddleware("service-name"))
+			e.GET("/test", tt.handler)
+
+			// When a request is served.
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+			forceFlush(t)
+
+			// Then the span status follows the response.
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			assert.EqualValues(t, tt.expectedStatus, rec.Code)
+			assert.Contains(t, spans[0].Attributes, attribute.Int64("http.response.status_code", tt.expectedStatus))
+			assert.Equal(t, tt.expectedCode, spans[0].Status.Code)
+
+			// And the returned errors are recorded as exceptions.
+			if tt.expectedEvent {
+				require.Len(t, spans[0].Events, 1)
+				assert.Equal(t, "exception", spans[0].Events[0].Name)
+			} else {
+				assert.Empty(t, spans[0].Events)
+			}
+		})
+	}
+}
+
+func TestTracerWithConfig(t *testing.T) {
+	// Given an echo server tracing all the requests but the healthcheck, with both call styles.
+	for name, tracer := range map[string]echo.MiddlewareFunc{
+		"Tracer": Tracer("service-name"),
+		"TracerWithConfig": TracerWithConfig(TracerConfig{
+			ServiceName: "service-name",
+			Skipper:     func(c echo.Context) bool { return c.Path() == "/healthcheck" },
+		}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			exporter := initTestTracer(t)
+			e := echo.New()
+			e.Use(tracer)
+			var handlerCtx context.Context
+			handler := func(c echo.Context) error {
+				handlerCtx = c.Request().Context()
+				return c.NoContent(http.StatusOK)
+			}
+			e.GET("/healthcheck", handler)
+			e.GET("/users", handler)
+
+			// When the requests are served, carrying a trace.
+			parentCtx, parent := trace.StartSpan(context.Background(), "client", "client")
+			parent.End()
+			parentTraceId, parentSpanId := trace.TraceIdAndSpanIdFromContext(parentCtx)
+			serve := func(path string) {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				trace.InjectTraceToHeader(parentCtx, req.Header)
+				e.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			serve("/healthcheck")
+			healthcheckTraceId, healthcheckSpanId := trace.TraceIdAndSpanIdFromContext(handlerCtx)
+			serve("/users")
+			forceFlush(t)
+
+			// Then the users request is traced.
+			var names []string
+			for _, span := range exporter.GetSpans() {
+				names = append(names, span.Name)
+			}
+			assert.Contains(t, names, "GET /users")
+
+			// And the healthcheck is only traced without the skipper, but keeps the trace of the request.
+			assert.Equal(t, parentTraceId, healthcheckTraceId)
+			if name == "TracerWithConfig" {
+				assert.NotContains(t, names, "GET /healthcheck")
+				assert.Equal(t, parentSpanId, healthcheckSpanId)
+			} else {
+				assert.Contains(t, names, "GET /healthcheck")
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package restmiddleware
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+// Transport is an http.RoundTripper tracing & logging the outgoing requests, e.g.
+//
+//	client := &http.Client{Transport: restmiddleware.NewTransport("service-name", nil)}
+//
+// Each request gets a client span, and its trace is propagated in the request headers with the global propagator.
+// The request & response are logged with the same shape, levels, body size limit & body content types as Logger,
+// the credentials headers redacted like the Logger does by default. The bodies aren't read ahead: the response is
+// logged, and the span ended, once the caller read the response body until the end or closed it.
+type Transport struct {
+	// serviceName is the name of the tracer.
+	serviceName string
+	// base does the requests.
+	base http.RoundTripper
+}
+
+// NewTransport returns a Transport doing the requests with base, http.DefaultTransport if nil.
+func NewTransport(serviceName string, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{serviceName: serviceName, base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := trace.StartSpan(req.Context(), t.serviceName, req.Method,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(clientRequestAttributes(req)...),
+	)
+
+	// A RoundTripper mustn't modify the request, so the headers are injected in a copy.
+	req = req.Clone(ctx)
+	trace.InjectTraceToHeader(ctx, req.Header)
+
+	reqCtx := map[string]any{
+		"method":  req.Method,
+		"url":     req.URL.Redacted(),
+		"query":   req.URL.Query(),
+		"headers": defaultHeaderPolicy.apply(req.Header),
+	}
+	var reqBody func() any
+	req.Body, reqBody = captureBody(req.Body, req.ContentLength, req.Header)
+
+	log.Info(ctx, log.Fields{
+		"request": reqCtx,
+	}, "Outgoing request: %s %s",
+		req.Method,
+		req.URL.Redacted(),
+	)
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		reqCtx["body"] = reqBody()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		log.Error(ctx, err, log.Fields{
+			"request": reqCtx,
+		}, "Failed outgoing request: %s %s",
+			req.Method,
+			req.URL.Redacted(),
+		)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+
+	// The response is logged & the span ended once the caller read the body, so streamed responses aren't delayed.
+	body, resBody := captureBody(res.Body, res.ContentLength, res.Header)
+	done := func() {
+		defer span.End()
+		reqCtx["body"] = reqBody()
+		fields := log.Fields{
+			"request": reqCtx,
+			"response": map[string]any{
+				"status":  res.StatusCode,
+				"headers": defaultHeaderPolicy.apply(res.Header),
+				"body":    resBody(),
+			},
+		}
+		switch {
+		case res.StatusCode >= http.StatusInternalServerError:
+			log.Error(ctx, nil, fields, "Incoming response: %s %s", req.Method, req.URL.Redacted())
+		case res.StatusCode >= http.StatusBadRequest:
+			log.Warn(ctx, fields, "Incoming response: %s %s", req.Method, req.URL.Redacted())
+		default:
+			log.Info(ctx, fields, "Incoming response: %s %s", req.Method, req.URL.Redacted())
+		}
+	}
+	// The body of a protocol switch is the connection, which can't be wrapped.
+	if isNoBody(res.Body) || res.StatusCode == http.StatusSwitchingProtocols {
+		done()
+		return res, nil
+	}
+	res.Body = &responseBody{ReadCloser: body, done: done}
+
+	return res, nil
+}
+
+// clientRequestAttributes returns the semantic convention attributes of the outgoing request.
+func clientRequestAttributes(req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		requestMethod(req.Method),
+		semconv.URLFull(req.URL.Redacted()),
+	}
+	host, portStr, err := net.SplitHostPort(req.URL.Host)
+	if err != nil {
+		host = req.URL.Host
+	}
+	if host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+	if port, err := strconv.Atoi(portStr); err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+
+	return attrs
+}
+
+// transportLogConfig applies the default body rules of Logger to the bodies of the outgoing requests.
+var transportLogConfig = LoggerConfig{MaxBodySize: MaxBodySize, SkipBodyContentTypes: DefaultSkipBodyContentTypes}
+
+// captureBody returns a body to read instead of the given one, capturing its first MaxBodySize bytes as it's read,
+// and a function returning the body to log once read, see bodyCapture: the larger ones are logged as a preview.
+// The multipart & binary bodies aren't captured.
+func captureBody(body io.ReadCloser, contentLength int64, header http.Header) (io.ReadCloser, func() any) {
+	if isNoBody(body) {
+		return body, func() any { return "" }
+	}
+	if reason, ok := transportLogConfig.skipBody(header.Get("Content-Type")); !ok {
+		return body, func() any { return reason }
+	}
+	capture := newBodyCapture(body, MaxBodySize)
+
+	return capture, func() any { return capture.toLog(header, contentLength) }
+}
+
+// responseBody calls done once, when the caller read the body until the end or closed it.
+type responseBody struct {
+	io.ReadCloser
+	done func()
+	once sync.Once
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+
+	return err
+}
@@ -0,0 +1,308 @@
+package restmiddleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+// captureLogs writes the default logger to the returned buffer until the end of the test.
+func captureLogs(t *testing.T, opts ...log.InitOptFn) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := log.Default()
+	t.Cleanup(func() { log.SetDefault(prev) })
+	l, err := log.New("service-name", "test", append(opts, log.WithWriter(&buf))...)
+	require.NoError(t, err)
+	log.SetDefault(l)
+
+	return &buf
+}
+
+// logLines returns the JSON log lines written to buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func TestTransport(t *testing.T) {
+	// Given a partner API & a client with the transport.
+	exporter := initTestTracer(t)
+	logs := captureLogs(t, log.WithFieldsToScrub([]string{"password"}))
+	var received http.Header
+	var receivedBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"42","password":"hunter2"}`))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport("service-name", srv.Client().Transport)}
+
+	// When a request is made within a trace.
+	ctx, parent := trace.StartSpan(context.Background(), "test", "test-transport")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/users?dry_run=true",
+		strings.NewReader(`{"name":"John","password":"secret"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	parent.End()
+	forceFlush(t)
+
+	// Then the request & response are passed through untouched.
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.JSONEq(t, `{"id":"42","password":"hunter2"}`, string(body))
+	assert.JSONEq(t, `{"name":"John","password":"secret"}`, string(receivedBody))
+	assert.Equal(t, "Bearer token", received.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("Traceparent"), "the original request mustn't be modified")
+
+	// And the trace is propagated from a client span.
+	var clientSpans int
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind != oteltrace.SpanKindClient {
+			continue
+		}
+		clientSpans++
+		assert.Equal(t, "POST", span.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Contains(t, received.Get("Traceparent"), span.SpanContext.SpanID().String())
+		assert.Equal(t, codes.Unset, span.Status.Code)
+	}
+	assert.Equal(t, 1, clientSpans)
+
+	// And the request & response are logged, scrubbed & with the credentials redacted.
+	lines := logLines(t, logs)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "Outgoing request: POST "+srv.URL+"/users?dry_run=true", lines[0]["message"])
+	request := lines[1]["context"].(map[string]any)["request"].(map[string]any)
+	assert.Equal(t, "POST", request["method"])
+	assert.Equal(t, map[string]any{"dry_run": []any{"true"}}, request["query"])
+	assert.Equal(t, map[string]any{"name": "John", "password": "***scrubbed***"}, request["body"])
+	assert.Equal(t, []any{"***redacted***"}, request["headers"].(map[string]any)["Authorization"])
+	response := lines[1]["context"].(map[string]any)["response"].(map[string]any)
+	assert.EqualValues(t, http.StatusCreated, response["status"])
+	assert.Equal(t, map[string]any{"id": "42", "password": "***scrubbed***"}, response["body"])
+}
+
+func TestTransport_LargeBody(t *testing.T) {
+	// Given a partner API returning a body larger than MaxBodySize, without Content-Length.
+	initTestTracer(t)
+	logs := captureLogs(t)
+	large := strings.Repeat("a", MaxBodySize+10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(large))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport("service-name", nil)}
+
+	// When a request is made.
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	// Then the whole body is still readable, but only its preview is logged, with the length read.
+	assert.Equal(t, large, string(body))
+	lines := logLines(t, logs)
+	require.Len(t, lines, 2)
+	response := lines[1]["context"].(map[string]any)["response"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"preview":         strings.Repeat("a", MaxBodySize),
+		"truncated":       true,
+		"original_length": float64(MaxBodySize + 10),
+	}, response["body"])
+}
+
+func TestTransport_Error(t *testing.T) {
+	// Given a transport failing to reach the server.
+	exporter := initTestTracer(t)
+	logs := captureLogs(t)
+	client := &http.Client{Transport: NewTransport("service-name", roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))}
+
+	// When a request is made.
+	_, err := client.Get("http://partner.example.com/status")
+
+	// Then the error is returned, recorded on the span & logged.
+	assert.ErrorContains(t, err, "connection refused")
+	forceFlush(t)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	lines := logLines(t, logs)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "error", lines[1]["level"])
+	assert.Equal(t, "Failed outgoing request: GET http://partner.example.com/status", lines[1]["message"])
+}
+
+func TestTransport_StreamedResponse(t *testing.T) {
+	// Given a partner API streaming its response, e.g. server-sent events.
+	exporter := initTestTracer(t)
+	logs := captureLogs(t)
+	next := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		<-next
+		_, _ = w.Write([]byte("data: 2\n\n"))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport("service-name", nil)}
+
+	// When a request is made, the response is returned before the whole body is sent.
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	first := make([]byte, len("data: 1\n\n"))
+	_, err = io.ReadFull(resp.Body, first)
+	require.NoError(t, err)
+	assert.Equal(t, "data: 1\n\n", string(first))
+
+	// And the response isn't logged, nor the span ended, until the body is read.
+	forceFlush(t)
+	assert.Empty(t, exporter.GetSpans())
+	assert.Len(t, logLines(t, logs), 1)
+
+	// Then once it's read, the span ends & the whole body is logged.
+	close(next)
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "data: 2\n\n", string(rest))
+	forceFlush(t)
+	assert.Len(t, exporter.GetSpans(), 1)
+	lines := logLines(t, logs)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "Incoming response: GET "+srv.URL, lines[0]["message"])
+	response := lines[0]["context"].(map[string]any)["response"].(map[string]any)
+	assert.Equal(t, "data: 1\n\ndata: 2\n\n", response["body"])
+}
+
+func TestTransport_BinaryBodies(t *testing.T) {
+	// Given a partner API returning an image.
+	initTestTracer(t)
+	logs := captureLogs(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport("service-name", nil)}
+
+	// When a multipart request is made, and the response closed without being read.
+	resp, err := client.Post(srv.URL, "multipart/form-data; boundary=xyz", strings.NewReader("--xyz--\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	// Then the bodies aren't logged, like with Logger.
+	lines := logLines(t, logs)
+	require.Len(t, lines, 2)
+	context := lines[1]["context"].(map[string]any)
+	assert.Equal(t, `Skipping body logging: Content-Type "multipart/form-data; boundary=xyz" not logged`,
+		context["request"].(map[string]any)["body"])
+	assert.Equal(t, `Skipping body logging: Content-Type "image/png" not logged`,
+		context["response"].(map[string]any)["body"])
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransport_KnownLargeBody(t *testing.T) {
+	// Given a partner API returning a body larger than MaxBodySize, with Content-Length.
+	initTestTracer(t)
+	logs := captureLogs(t)
+	large := strings.Repeat("a", MaxBodySize+10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+		_, _ = w.Write([]byte(large))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport("service-name", nil)}
+
+	// When a request is made.
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	// Then the whole body is readable, and only its preview is logged, like with Logger.
+	assert.Equal(t, large, string(body))
+	lines := logLines(t, logs)
+	require.Len(t, lines, 2)
+	response := lines[1]["context"].(map[string]any)["response"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"preview":         strings.Repeat("a", MaxBodySize),
+		"truncated":       true,
+		"original_length": float64(MaxBodySize + 10),
+	}, response["body"])
+}
+
+func TestTransport_ErrorResponses(t *testing.T) {
+	for _, tt := range []struct {
+		status        int
+		expectedLevel string
+	}{
+		{status: http.StatusOK, expectedLevel: "info"},
+		{status: http.StatusNotFound, expectedLevel: "warn"},
+		{status: http.StatusBadGateway, expectedLevel: "error"},
+	} {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			// Given a partner API responding with the status.
+			initTestTracer(t)
+			logs := captureLogs(t)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			client := &http.Client{Transport: NewTransport("service-name", nil)}
+
+			// When a request is made.
+			resp, err := client.Get(srv.URL)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			// Then the response is logged at the level of its status, like with Logger.
+			lines := logLines(t, logs)
+			require.Len(t, lines, 2)
+			assert.Equal(t, "Incoming response: GET "+srv.URL, lines[1]["message"])
+			assert.Equal(t, tt.expectedLevel, lines[1]["level"])
+		})
+	}
+}
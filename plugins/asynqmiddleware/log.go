@@ -14,7 +14,7 @@ func Logger() asynq.MiddlewareFunc {
 		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
 			logFields := log.Fields{
 				"queue":   task.Type(), // We use task type as the queue name.
-				"payload": string(task.Payload()),
+				"payload": string(Payload(task)),
 			}
 			log.Info(ctx, logFields, "Processing queue message...")
 
@@ -0,0 +1,134 @@
+package asynqmiddleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+// envelopePrefix marks the payloads wrapped with the trace context, as asynq tasks don't have headers.
+// A wrapped payload is: envelopePrefix + the JSON trace carrier + "\n" + the original payload.
+var envelopePrefix = []byte("logtrace.v1\n")
+
+// Enqueuer enqueues the tasks, e.g. *asynq.Client.
+type Enqueuer interface {
+	EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+// traceEnvelopeOpt is the asynq.OptionType of WithTraceEnvelope, out of the range of the asynq ones.
+const traceEnvelopeOpt asynq.OptionType = -1
+
+type traceEnvelopeOption struct{}
+
+func (traceEnvelopeOption) String() string         { return "TraceEnvelope()" }
+func (traceEnvelopeOption) Type() asynq.OptionType { return traceEnvelopeOpt }
+func (traceEnvelopeOption) Value() interface{}     { return true }
+
+// WithTraceEnvelope is an option of Enqueue & NewTask wrapping the payload in an envelope carrying the trace context,
+// as asynq tasks don't have headers, so the Tracer middleware continues the trace of the producer.
+//
+// It's opt-in as the handlers of these tasks must read their payload with Payload: task.Payload() is the envelope.
+// Only use it once all the workers processing the task type are ready for it.
+func WithTraceEnvelope() asynq.Option {
+	return traceEnvelopeOption{}
+}
+
+// Enqueue enqueues a task in a producer span named after the task type.
+// With WithTraceEnvelope, the task carries the trace context of the span, which the Tracer middleware continues.
+func Enqueue(
+	ctx context.Context,
+	serviceName string,
+	client Enqueuer,
+	typename string,
+	payload []byte,
+	opts ...asynq.Option,
+) (*asynq.TaskInfo, error) {
+	ctx, span := trace.StartSpan(ctx, serviceName, typename,
+		oteltrace.WithSpanKind(oteltrace.SpanKindProducer),
+		oteltrace.WithAttributes(
+			semconv.MessagingSystemKey.String("asynq"),
+			semconv.MessagingOperationTypePublish,
+		),
+	)
+	defer span.End()
+
+	info, err := client.EnqueueContext(ctx, NewTask(ctx, typename, payload, opts...))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(
+		semconv.MessagingMessageID(info.ID),
+		semconv.MessagingDestinationName(info.Queue),
+	)
+
+	return info, nil
+}
+
+// NewTask returns a task, carrying the trace context of ctx in its payload with WithTraceEnvelope, see Enqueue.
+func NewTask(ctx context.Context, typename string, payload []byte, opts ...asynq.Option) *asynq.Task {
+	taskOpts := make([]asynq.Option, 0, len(opts))
+	envelope := false
+	for _, opt := range opts {
+		if opt.Type() == traceEnvelopeOpt {
+			envelope = true
+			continue
+		}
+		taskOpts = append(taskOpts, opt)
+	}
+	if envelope {
+		payload = wrapPayload(ctx, payload)
+	}
+
+	return asynq.NewTask(typename, payload, taskOpts...)
+}
+
+// Payload returns the original payload of the task, without the envelope of WithTraceEnvelope if any.
+func Payload(task *asynq.Task) []byte {
+	_, payload, _ := unwrapPayload(task.Payload())
+
+	return payload
+}
+
+func wrapPayload(ctx context.Context, payload []byte) []byte {
+	carrier := map[string]string{}
+	trace.InjectTraceToMap(ctx, carrier)
+	if len(carrier) == 0 {
+		return payload
+	}
+	// A map of strings can't fail to marshal.
+	carrierJSON, _ := json.Marshal(carrier)
+
+	wrapped := make([]byte, 0, len(envelopePrefix)+len(carrierJSON)+1+len(payload))
+	wrapped = append(wrapped, envelopePrefix...)
+	wrapped = append(wrapped, carrierJSON...)
+	wrapped = append(wrapped, '\n')
+
+	return append(wrapped, payload...)
+}
+
+// unwrapPayload returns the trace carrier & the original payload of a wrapped payload.
+// ok is false if the payload isn't wrapped, e.g. enqueued without NewTask.
+func unwrapPayload(payload []byte) (carrier map[string]string, original []byte, ok bool) {
+	rest, found := bytes.CutPrefix(payload, envelopePrefix)
+	if !found {
+		return nil, payload, false
+	}
+	carrierJSON, original, found := bytes.Cut(rest, []byte("\n"))
+	if !found {
+		return nil, payload, false
+	}
+	if err := json.Unmarshal(carrierJSON, &carrier); err != nil {
+		return nil, payload, false
+	}
+
+	return carrier, original, true
+}
@@ -4,17 +4,47 @@ import (
 	"context"
 
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
 	"github.com/pixel8labs/logtrace/trace"
 )
 
-// Tracer is an asynq middleware that will start a new span for each request.
-// TODO: propagate the trace from the incoming message.
+// Tracer is an asynq middleware that will start a new consumer span for each task, named after the task type.
+// The span continues the trace of the producer if the task was enqueued with WithTraceEnvelope.
+// The next handlers get the task as-is, so they read its original payload with Payload.
 func Tracer(serviceName string) asynq.MiddlewareFunc {
 	return func(next asynq.Handler) asynq.Handler {
 		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
-			ctx, span := trace.StartSpan(ctx, serviceName, task.Type())
+			if carrier, _, ok := unwrapPayload(task.Payload()); ok {
+				ctx = trace.ExtractTraceFromMap(ctx, carrier)
+			}
+
+			attrs := []attribute.KeyValue{
+				semconv.MessagingSystemKey.String("asynq"),
+				semconv.MessagingOperationTypeDeliver,
+			}
+			if id, ok := asynq.GetTaskID(ctx); ok {
+				attrs = append(attrs, semconv.MessagingMessageID(id))
+			}
+			if queue, ok := asynq.GetQueueName(ctx); ok {
+				attrs = append(attrs, semconv.MessagingDestinationName(queue))
+			}
+			ctx, span := trace.StartSpan(ctx, serviceName, task.Type(),
+				oteltrace.WithSpanKind(oteltrace.SpanKindConsumer),
+				oteltrace.WithAttributes(attrs...),
+			)
 			defer span.End()
-			return next.ProcessTask(ctx, task)
+
+			if err := next.ProcessTask(ctx, task); err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return err
+			}
+
+			return nil
 		})
 	}
 }
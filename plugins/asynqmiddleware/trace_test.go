@@ -0,0 +1,170 @@
+package asynqmiddleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+// initTestTracer records the spans in memory until the end of the test.
+func initTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	_, err := trace.InitTracer(trace.WithExporter(exporter))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := trace.InitTracer()
+		require.NoError(t, err)
+	})
+
+	return exporter
+}
+
+func spansByKind(t *testing.T, exporter *tracetest.InMemoryExporter) map[oteltrace.SpanKind]tracetest.SpanStub {
+	t.Helper()
+	tp, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	require.True(t, ok)
+	require.NoError(t, tp.ForceFlush(context.Background()))
+
+	res := map[oteltrace.SpanKind]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		res[span.SpanKind] = span
+	}
+
+	return res
+}
+
+func TestEnqueueAndTracer(t *testing.T) {
+	// Given a Redis server, an asynq server with the Tracer middleware & a client.
+	exporter := initTestTracer(t)
+	redis := miniredis.RunT(t)
+	redisOpt := asynq.RedisClientOpt{Addr: redis.Addr()}
+
+	processed := make(chan []byte, 1)
+	var handlerCtx context.Context
+	mux := asynq.NewServeMux()
+	mux.Use(Tracer("worker"))
+	mux.HandleFunc("email:send", func(ctx context.Context, task *asynq.Task) error {
+		handlerCtx = ctx
+		// The task keeps its ResultWriter.
+		if _, err := task.ResultWriter().Write([]byte("sent")); err != nil {
+			return err
+		}
+		processed <- Payload(task)
+		return nil
+	})
+	srv := asynq.NewServer(redisOpt, asynq.Config{Concurrency: 1, LogLevel: asynq.FatalLevel})
+	require.NoError(t, srv.Start(mux))
+	defer srv.Shutdown()
+
+	client := asynq.NewClient(redisOpt)
+	defer client.Close()
+
+	// When a task is enqueued within a trace.
+	ctx, parent := trace.StartSpan(context.Background(), "api", "POST /signup")
+	info, err := Enqueue(ctx, "api", client, "email:send", []byte(`{"to":"user@example.com"}`),
+		WithTraceEnvelope(), asynq.Retention(time.Hour))
+	parent.End()
+	require.NoError(t, err)
+
+	// Then the handler gets the original payload & writes its result.
+	var payload []byte
+	select {
+	case payload = <-processed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the task wasn't processed")
+	}
+	assert.JSONEq(t, `{"to":"user@example.com"}`, string(payload))
+	inspector := asynq.NewInspector(redisOpt)
+	defer inspector.Close()
+	require.Eventually(t, func() bool {
+		task, err := inspector.GetTaskInfo(info.Queue, info.ID)
+		return err == nil && string(task.Result) == "sent"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// And the consumer span is a child of the producer span, in the trace of the request.
+	parentTraceId, _ := trace.TraceIdAndSpanIdFromContext(ctx)
+	handlerTraceId, _ := trace.TraceIdAndSpanIdFromContext(handlerCtx)
+	assert.Equal(t, parentTraceId, handlerTraceId)
+
+	// The consumer span ends right after the handler returns.
+	var spans map[oteltrace.SpanKind]tracetest.SpanStub
+	require.Eventually(t, func() bool {
+		spans = spansByKind(t, exporter)
+		return len(spans) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	producer, consumer := spans[oteltrace.SpanKindProducer], spans[oteltrace.SpanKindConsumer]
+	assert.Equal(t, "email:send", producer.Name)
+	assert.Equal(t, "email:send", consumer.Name)
+	assert.Equal(t, parentTraceId, producer.SpanContext.TraceID().String())
+	assert.Equal(t, producer.SpanContext.SpanID(), consumer.Parent.SpanID())
+	assert.Contains(t, consumer.Attributes, semconv.MessagingMessageID(info.ID))
+}
+
+func TestTracer_WithoutTrace(t *testing.T) {
+	// Given a task enqueued without trace context.
+	exporter := initTestTracer(t)
+	task := asynq.NewTask("email:send", []byte("payload"))
+
+	// When it's processed with the Tracer middleware.
+	var handled *asynq.Task
+	err := Tracer("worker")(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		handled = task
+		return errors.New("smtp down")
+	})).ProcessTask(context.Background(), task)
+
+	// Then the task is passed as-is, in a new root trace recording the error.
+	assert.EqualError(t, err, "smtp down")
+	assert.Same(t, task, handled)
+	consumer := spansByKind(t, exporter)[oteltrace.SpanKindConsumer]
+	assert.False(t, consumer.Parent.IsValid())
+	assert.Equal(t, "smtp down", consumer.Status.Description)
+}
+
+func TestNewTask(t *testing.T) {
+	initTestTracer(t)
+	ctx, span := trace.StartSpan(context.Background(), "test", "test")
+	defer span.End()
+
+	// Without WithTraceEnvelope, the payload is untouched.
+	task := NewTask(ctx, "email:send", []byte("payload"), asynq.Queue("critical"))
+	assert.Equal(t, []byte("payload"), task.Payload())
+
+	// With it, the payload is wrapped, and Payload gives back the original one.
+	task = NewTask(ctx, "email:send", []byte("payload"), WithTraceEnvelope(), asynq.Queue("critical"))
+	assert.NotEqual(t, []byte("payload"), task.Payload())
+	assert.Equal(t, []byte("payload"), Payload(task))
+}
+
+func TestUnwrapPayload(t *testing.T) {
+	initTestTracer(t)
+
+	// A payload without trace context isn't wrapped.
+	assert.Equal(t, []byte("payload"), wrapPayload(context.Background(), []byte("payload")))
+
+	// A wrapped payload gives back the carrier & the original payload, even with new lines.
+	ctx, span := trace.StartSpan(context.Background(), "test", "test")
+	defer span.End()
+	carrier, payload, ok := unwrapPayload(wrapPayload(ctx, []byte("line 1\nline 2")))
+	require.True(t, ok)
+	assert.Contains(t, carrier, "traceparent")
+	assert.Equal(t, []byte("line 1\nline 2"), payload)
+
+	// An unknown payload is returned as-is.
+	_, payload, ok = unwrapPayload([]byte("logtrace.v1\nnot json\npayload"))
+	assert.False(t, ok)
+	assert.Equal(t, []byte("logtrace.v1\nnot json\npayload"), payload)
+}
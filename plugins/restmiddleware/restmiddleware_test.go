@@ -0,0 +1,88 @@
+package restmiddleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pixel8labs/logtrace/log"
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func TestDeprecatedMiddlewares(t *testing.T) {
+	// Given an echo server with the deprecated middlewares.
+	_, err := trace.InitTracer()
+	require.NoError(t, err)
+	defer func() { _ = trace.Shutdown(context.Background()) }()
+	var buf bytes.Buffer
+	prev := log.Default()
+	defer log.SetDefault(prev)
+	l, err := log.New("service-name", "test", log.WithWriter(&buf))
+	require.NoError(t, err)
+	log.SetDefault(l)
+
+	e := echo.New()
+	e.Use(Tracer("service-name"), Logger())
+	var handlerCtx context.Context
+	e.GET("/users/:id", func(c echo.Context) error {
+		handlerCtx = c.Request().Context()
+		return c.String(http.StatusOK, "OK")
+	})
+
+	// When a request carrying a trace is served.
+	parentCtx, parent := trace.StartSpan(context.Background(), "client", "client")
+	parent.End()
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	trace.InjectTraceToHeader(parentCtx, req.Header)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Then they behave like the ones of the middleware package: the trace is continued & the response logged.
+	parentTraceId, _ := trace.TraceIdAndSpanIdFromContext(parentCtx)
+	handlerTraceId, _ := trace.TraceIdAndSpanIdFromContext(handlerCtx)
+	assert.NotEmpty(t, parentTraceId)
+	assert.Equal(t, parentTraceId, handlerTraceId)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"message":"Outgoing response: GET /users/42"`)
+	assert.Contains(t, lines[1], `"route":"/users/:id"`)
+}
+
+func TestDeprecatedTracer_Attributes(t *testing.T) {
+	// Given an echo server with the deprecated tracing middleware.
+	exporter := tracetest.NewInMemoryExporter()
+	_, err := trace.InitTracer(trace.WithExporter(exporter))
+	require.NoError(t, err)
+	defer func() { _ = trace.Shutdown(context.Background()) }()
+	e := echo.New()
+	e.Use(Tracer("service-name"))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return errors.New("database unavailable")
+	})
+
+	// When a request fails.
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	tp, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	require.True(t, ok)
+	require.NoError(t, tp.ForceFlush(context.Background()))
+
+	// Then its span has the attributes & error status of the one of the middleware package.
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /users/:id", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, attribute.String("http.route", "/users/:id"))
+	assert.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
@@ -2,22 +2,13 @@ package restmiddleware
 
 import (
 	"github.com/labstack/echo/v4"
-	"github.com/pixel8labs/logtrace/trace"
+
+	echologtrace "github.com/pixel8labs/logtrace/middleware"
 )
 
-// Tracer is a middleware that creates a new span for each incoming request.
-// TODO: propagate the trace from the request headers.
+// Tracer creates a new server span for each incoming request.
+//
+// Deprecated: Use Tracer or TracerWithConfig of github.com/pixel8labs/logtrace/middleware instead.
 func Tracer(serviceName string) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			req := c.Request()
-			ctx, span := trace.StartSpan(req.Context(), serviceName, c.Path())
-
-			defer span.End()
-
-			c.SetRequest(req.WithContext(ctx))
-
-			return next(c)
-		}
-	}
+	return echologtrace.Tracer(serviceName)
 }
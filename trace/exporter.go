@@ -0,0 +1,159 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// exporterOpener creates a span exporter. It's called by InitTracer, after all the options are applied.
+type exporterOpener func(ctx context.Context) (sdktrace.SpanExporter, error)
+
+// WithOTLPHTTP exports the spans to an OTLP collector over HTTP.
+// The endpoint is either a URL (e.g. "http://localhost:4318/v1/traces") or a host:port (e.g. "localhost:4318").
+// If empty, the standard OTEL_EXPORTER_OTLP_* env vars are used.
+func WithOTLPHTTP(endpoint string, opts ...otlptracehttp.Option) InitOptFn {
+	return func(config *initConfig) {
+		config.exporters = append(config.exporters, func(ctx context.Context) (sdktrace.SpanExporter, error) {
+			exporterOpts := opts
+			if strings.Contains(endpoint, "://") {
+				exporterOpts = append([]otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}, opts...)
+			} else if endpoint != "" {
+				exporterOpts = append([]otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}, opts...)
+			}
+			return otlptracehttp.New(ctx, exporterOpts...)
+		})
+	}
+}
+
+// WithOTLPGRPC exports the spans to an OTLP collector over gRPC.
+// The endpoint is either a URL (e.g. "http://localhost:4317") or a host:port (e.g. "localhost:4317").
+// If empty, the standard OTEL_EXPORTER_OTLP_* env vars are used.
+func WithOTLPGRPC(endpoint string, opts ...otlptracegrpc.Option) InitOptFn {
+	return func(config *initConfig) {
+		config.exporters = append(config.exporters, func(ctx context.Context) (sdktrace.SpanExporter, error) {
+			exporterOpts := opts
+			if strings.Contains(endpoint, "://") {
+				exporterOpts = append([]otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpoint)}, opts...)
+			} else if endpoint != "" {
+				exporterOpts = append([]otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}, opts...)
+			}
+			return otlptracegrpc.New(ctx, exporterOpts...)
+		})
+	}
+}
+
+// WithStdoutExporter writes the spans to stdout as JSON, e.g. for local debugging.
+func WithStdoutExporter() InitOptFn {
+	return func(config *initConfig) {
+		config.exporters = append(config.exporters, func(context.Context) (sdktrace.SpanExporter, error) {
+			return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		})
+	}
+}
+
+// WithFileExporter appends the spans to the file on the given path as JSON, one span per line.
+// The file (and its parent directory) will be created if it doesn't exist.
+func WithFileExporter(path string) InitOptFn {
+	return func(config *initConfig) {
+		config.exporters = append(config.exporters, func(context.Context) (sdktrace.SpanExporter, error) {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return nil, fmt.Errorf("trace: create directory for %s: %w", path, err)
+			}
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, fmt.Errorf("trace: open %s: %w", path, err)
+			}
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+			if err != nil {
+				_ = file.Close()
+				return nil, err
+			}
+			return fileExporter{SpanExporter: exporter, file: file}, nil
+		})
+	}
+}
+
+// WithExporter exports the spans with the given exporter, e.g. a vendor-specific one.
+func WithExporter(exporter sdktrace.SpanExporter) InitOptFn {
+	return func(config *initConfig) {
+		config.exporters = append(config.exporters, func(context.Context) (sdktrace.SpanExporter, error) {
+			return exporter, nil
+		})
+	}
+}
+
+// WithBatchTimeout sets the maximum delay before the batched spans are exported. Defaults to 5 seconds.
+func WithBatchTimeout(timeout time.Duration) InitOptFn {
+	return func(config *initConfig) {
+		config.batchOpts = append(config.batchOpts, sdktrace.WithBatchTimeout(timeout))
+	}
+}
+
+// WithExportTimeout sets how long an export can run before being cancelled. Defaults to 30 seconds.
+func WithExportTimeout(timeout time.Duration) InitOptFn {
+	return func(config *initConfig) {
+		config.batchOpts = append(config.batchOpts, sdktrace.WithExportTimeout(timeout))
+	}
+}
+
+// fileExporter closes the file when the exporter is shut down.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.file.Close())
+}
+
+// exportersFromEnv returns the exporters configured by the standard env vars, used when no exporter is given:
+//   - OTEL_TRACES_EXPORTER: "otlp", "console" or "none".
+//   - If not set, an OTLP exporter is used when OTEL_EXPORTER_OTLP_ENDPOINT/OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set.
+//   - OTEL_EXPORTER_OTLP_TRACES_PROTOCOL/OTEL_EXPORTER_OTLP_PROTOCOL picks between "grpc" & "http/protobuf" (default).
+//
+// The other OTEL_EXPORTER_OTLP_* env vars (headers, timeout, TLS...) are read by the exporters themselves.
+func exportersFromEnv() ([]exporterOpener, error) {
+	names := os.Getenv("OTEL_TRACES_EXPORTER")
+	if names == "" {
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+			return nil, nil
+		}
+		names = "otlp"
+	}
+
+	cfg := &initConfig{}
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "otlp":
+			protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+			if protocol == "" {
+				protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+			}
+			switch protocol {
+			case "grpc":
+				WithOTLPGRPC("")(cfg)
+			case "", "http/protobuf":
+				WithOTLPHTTP("")(cfg)
+			default:
+				return nil, fmt.Errorf("trace: unsupported OTLP protocol %q", protocol)
+			}
+		case "console":
+			WithStdoutExporter()(cfg)
+		case "none", "":
+		default:
+			return nil, fmt.Errorf("trace: unsupported exporter %q", name)
+		}
+	}
+
+	return cfg.exporters, nil
+}
@@ -0,0 +1,108 @@
+package trace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func forceFlush(t *testing.T) {
+	t.Helper()
+	tp, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	require.True(t, ok)
+	require.NoError(t, tp.ForceFlush(context.Background()))
+}
+
+// resetTracer restores the default tracer at the end of the test.
+// Call it before t.Setenv, so the env is restored first.
+func resetTracer(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		_, err := trace.InitTracer()
+		require.NoError(t, err)
+	})
+}
+
+func newCollector(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" && r.Header.Get("Content-Type") == "application/x-protobuf" {
+			requests.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func TestInitTracer_WithFileExporter(t *testing.T) {
+	// Given the tracer exporting to a file.
+	path := filepath.Join(t.TempDir(), "traces", "spans.json")
+	resetTracer(t)
+	_, err := trace.InitTracer(trace.WithFileExporter(path))
+	require.NoError(t, err)
+
+	// When we end a span & flush.
+	_, span := trace.StartSpan(context.Background(), "test", "test-file-exporter")
+	span.End()
+	forceFlush(t)
+
+	// Then the span is written to the file.
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"test-file-exporter"`)
+}
+
+func TestInitTracer_WithOTLPHTTP(t *testing.T) {
+	// Given the tracer exporting to an OTLP collector over HTTP.
+	srv, requests := newCollector(t)
+	resetTracer(t)
+	_, err := trace.InitTracer(trace.WithOTLPHTTP(srv.URL + "/v1/traces"))
+	require.NoError(t, err)
+
+	// When we end a span & flush.
+	_, span := trace.StartSpan(context.Background(), "test", "test-otlp-http")
+	span.End()
+	forceFlush(t)
+
+	// Then the span is sent to the collector.
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+func TestInitTracer_OTLPFromEnv(t *testing.T) {
+	// Given the collector endpoint in the standard env var.
+	srv, requests := newCollector(t)
+	resetTracer(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	_, err := trace.InitTracer()
+	require.NoError(t, err)
+
+	// When we end a span & flush.
+	_, span := trace.StartSpan(context.Background(), "test", "test-otlp-env")
+	span.End()
+	forceFlush(t)
+
+	// Then the span is sent to the collector.
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+func TestInitTracer_UnsupportedExporterFromEnv(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "carrier-pigeon")
+
+	_, err := trace.InitTracer()
+
+	assert.Error(t, err)
+}
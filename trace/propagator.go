@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// The propagator names accepted by WithPropagators & the OTEL_PROPAGATORS env var.
+const (
+	// PropagatorTraceContext is the W3C traceparent/tracestate headers.
+	PropagatorTraceContext = "tracecontext"
+	// PropagatorBaggage is the W3C baggage header.
+	PropagatorBaggage = "baggage"
+	// PropagatorB3 is the B3 single "b3" header.
+	PropagatorB3 = "b3"
+	// PropagatorB3Multi is the B3 multiple "x-b3-*" headers.
+	PropagatorB3Multi = "b3multi"
+	// PropagatorJaeger is the Jaeger "uber-trace-id" header.
+	PropagatorJaeger = "jaeger"
+	// PropagatorNone disables the propagation.
+	PropagatorNone = "none"
+)
+
+// defaultPropagators are used when neither WithPropagators nor OTEL_PROPAGATORS is set.
+var defaultPropagators = []string{PropagatorTraceContext, PropagatorBaggage}
+
+// WithPropagators sets the propagators used to inject & extract the trace context, in order, e.g.
+// WithPropagators(PropagatorTraceContext, PropagatorBaggage, PropagatorB3) to also talk with B3 proxies.
+// On extraction, the last propagator finding a trace wins.
+// Defaults to the OTEL_PROPAGATORS env var, then "tracecontext,baggage".
+func WithPropagators(names ...string) InitOptFn {
+	return func(config *initConfig) {
+		config.propagators = names
+	}
+}
+
+// newPropagator returns the composite propagator of the configured names.
+func newPropagator(cfg *initConfig) (propagation.TextMapPropagator, error) {
+	names := cfg.propagators
+	if names == nil {
+		names = defaultPropagators
+		if env := os.Getenv("OTEL_PROPAGATORS"); env != "" {
+			names = strings.Split(env, ",")
+		}
+	}
+
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		case PropagatorNone:
+			return propagation.NewCompositeTextMapPropagator(), nil
+		case "":
+		default:
+			return nil, fmt.Errorf("trace: unsupported propagator %q", name)
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
@@ -0,0 +1,94 @@
+package trace_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func TestInjectTraceToMapAndExtractTraceFromMap_Propagators(t *testing.T) {
+	for _, tc := range []struct {
+		propagator string
+		key        string
+	}{
+		{propagator: trace.PropagatorTraceContext, key: "traceparent"},
+		{propagator: trace.PropagatorB3, key: "b3"},
+		{propagator: trace.PropagatorB3Multi, key: "x-b3-traceid"},
+		{propagator: trace.PropagatorJaeger, key: "uber-trace-id"},
+	} {
+		t.Run(tc.propagator, func(t *testing.T) {
+			// Given the tracer initialized with the propagator.
+			resetTracer(t)
+			_, err := trace.InitTracer(trace.WithPropagators(tc.propagator))
+			require.NoError(t, err)
+
+			// And a context with a trace.
+			ctx, span := trace.StartSpan(context.Background(), "test", "test-propagators")
+			defer span.End()
+			originalTraceId, originalSpanId := trace.TraceIdAndSpanIdFromContext(ctx)
+
+			// When we inject the trace to a map.
+			mapStringToString := make(map[string]string)
+			trace.InjectTraceToMap(ctx, mapStringToString)
+
+			// Then the propagator header is set.
+			assert.Contains(t, mapStringToString, tc.key)
+
+			// And extracting it gives the same trace id and span id.
+			newCtx := trace.ExtractTraceFromMap(context.Background(), mapStringToString)
+			newTraceId, newSpanId := trace.TraceIdAndSpanIdFromContext(newCtx)
+			assert.Equal(t, originalTraceId, newTraceId)
+			assert.Equal(t, originalSpanId, newSpanId)
+		})
+	}
+}
+
+func TestInjectTraceToMapAndExtractTraceFromMap_Composite(t *testing.T) {
+	// Given the tracer initialized with the propagators from the env.
+	resetTracer(t)
+	t.Setenv("OTEL_PROPAGATORS", "tracecontext,baggage,b3,jaeger")
+	_, err := trace.InitTracer()
+	require.NoError(t, err)
+
+	// And a context with a trace & a baggage.
+	ctx, span := trace.StartSpan(context.Background(), "test", "test-composite")
+	defer span.End()
+	member, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	// When we inject the trace to a map.
+	mapStringToString := make(map[string]string)
+	trace.InjectTraceToMap(ctx, mapStringToString)
+
+	// Then all the propagators headers are set.
+	for _, key := range []string{"traceparent", "baggage", "b3", "uber-trace-id"} {
+		assert.Contains(t, mapStringToString, key)
+	}
+
+	// And each of them alone is enough to extract the trace.
+	originalTraceId, originalSpanId := trace.TraceIdAndSpanIdFromContext(ctx)
+	for _, key := range []string{"traceparent", "b3", "uber-trace-id"} {
+		newCtx := trace.ExtractTraceFromMap(context.Background(), map[string]string{key: mapStringToString[key]})
+		newTraceId, newSpanId := trace.TraceIdAndSpanIdFromContext(newCtx)
+		assert.Equal(t, originalTraceId, newTraceId, key)
+		assert.Equal(t, originalSpanId, newSpanId, key)
+	}
+
+	// And the baggage is extracted too.
+	newCtx := trace.ExtractTraceFromMap(context.Background(), mapStringToString)
+	assert.Equal(t, "acme", baggage.FromContext(newCtx).Member("tenant").Value())
+}
+
+func TestInitTracer_UnsupportedPropagator(t *testing.T) {
+	resetTracer(t)
+	_, err := trace.InitTracer(trace.WithPropagators("xray"))
+	assert.ErrorContains(t, err, `unsupported propagator "xray"`)
+}
@@ -0,0 +1,167 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceInfo describes the service emitting the spans, as set on the resource of the tracer provider.
+type ServiceInfo struct {
+	Name        string
+	Version     string
+	Environment string
+}
+
+// service is the service info resolved by the last InitTracer.
+var service atomic.Pointer[ServiceInfo]
+
+// Service returns the service info resolved by the last InitTracer, so the logs can use the same values
+// as the spans. It's empty if InitTracer wasn't called.
+func Service() ServiceInfo {
+	info := service.Load()
+	if info == nil {
+		return ServiceInfo{}
+	}
+
+	return *info
+}
+
+// WithServiceName sets the service.name resource attribute.
+// Defaults to the OTEL_SERVICE_NAME env var, then the SERVICE_NAME one.
+func WithServiceName(name string) InitOptFn {
+	return func(config *initConfig) {
+		config.serviceName = name
+	}
+}
+
+// WithServiceVersion sets the service.version resource attribute.
+// Defaults to the SERVICE_VERSION env var.
+func WithServiceVersion(version string) InitOptFn {
+	return func(config *initConfig) {
+		config.serviceVersion = version
+	}
+}
+
+// WithEnvironment sets the deployment.environment resource attribute.
+// Defaults to the APP_ENV env var.
+func WithEnvironment(env string) InitOptFn {
+	return func(config *initConfig) {
+		config.environment = env
+	}
+}
+
+// WithResourceAttributes adds attributes to the resource, on top of the detected ones.
+func WithResourceAttributes(attrs ...attribute.KeyValue) InitOptFn {
+	return func(config *initConfig) {
+		config.resourceAttrs = append(config.resourceAttrs, attrs...)
+	}
+}
+
+// newResource builds the resource of the tracer provider, from lowest to highest precedence:
+//   - the SDK defaults (e.g. unknown_service:<executable>),
+//   - the host, process, container & Kubernetes detectors,
+//   - the SERVICE_NAME, SERVICE_VERSION & APP_ENV env vars,
+//   - the OTEL_SERVICE_NAME & OTEL_RESOURCE_ATTRIBUTES env vars,
+//   - the options.
+func newResource(ctx context.Context, cfg *initConfig) (*sdkresource.Resource, error) {
+	var fallbackAttrs []attribute.KeyValue
+	if name := os.Getenv("SERVICE_NAME"); name != "" {
+		fallbackAttrs = append(fallbackAttrs, semconv.ServiceName(name))
+	}
+	if version := os.Getenv("SERVICE_VERSION"); version != "" {
+		fallbackAttrs = append(fallbackAttrs, semconv.ServiceVersion(version))
+	}
+	if env := os.Getenv("APP_ENV"); env != "" {
+		fallbackAttrs = append(fallbackAttrs, semconv.DeploymentEnvironment(env))
+	}
+
+	var attrs []attribute.KeyValue
+	if cfg.serviceName != "" {
+		attrs = append(attrs, semconv.ServiceName(cfg.serviceName))
+	}
+	if cfg.serviceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.serviceVersion))
+	}
+	if cfg.environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(cfg.environment))
+	}
+	attrs = append(attrs, cfg.resourceAttrs...)
+
+	detected, err := sdkresource.New(ctx,
+		sdkresource.WithTelemetrySDK(),
+		sdkresource.WithHost(),
+		sdkresource.WithProcessPID(),
+		sdkresource.WithProcessExecutableName(),
+		sdkresource.WithProcessRuntimeName(),
+		sdkresource.WithProcessRuntimeVersion(),
+		sdkresource.WithContainer(),
+		sdkresource.WithDetectors(k8sDetector{}),
+		sdkresource.WithAttributes(fallbackAttrs...),
+		sdkresource.WithFromEnv(),
+		sdkresource.WithAttributes(attrs...),
+	)
+	if err != nil {
+		// A detector failing (e.g. no container id outside of a container) shouldn't prevent the tracing.
+		if !errors.Is(err, sdkresource.ErrPartialResource) {
+			return nil, err
+		}
+		otel.Handle(err)
+	}
+
+	return sdkresource.Merge(sdkresource.Default(), detected)
+}
+
+func serviceInfoFromResource(res *sdkresource.Resource) ServiceInfo {
+	set := res.Set()
+	name, _ := set.Value(semconv.ServiceNameKey)
+	version, _ := set.Value(semconv.ServiceVersionKey)
+	env, _ := set.Value(semconv.DeploymentEnvironmentKey)
+
+	return ServiceInfo{
+		Name:        name.AsString(),
+		Version:     version.AsString(),
+		Environment: env.AsString(),
+	}
+}
+
+// k8sDetector detects the Kubernetes attributes from the env vars usually set with the downward API, e.g.
+//
+//	env:
+//	  - name: K8S_POD_NAME
+//	    valueFrom:
+//	      fieldRef:
+//	        fieldPath: metadata.name
+type k8sDetector struct{}
+
+func (k8sDetector) Detect(context.Context) (*sdkresource.Resource, error) {
+	var attrs []attribute.KeyValue
+	for _, attr := range []struct {
+		fn   func(string) attribute.KeyValue
+		envs []string
+	}{
+		{fn: semconv.K8SPodName, envs: []string{"K8S_POD_NAME", "POD_NAME"}},
+		{fn: semconv.K8SPodUID, envs: []string{"K8S_POD_UID", "POD_UID"}},
+		{fn: semconv.K8SNamespaceName, envs: []string{"K8S_NAMESPACE_NAME", "K8S_NAMESPACE", "POD_NAMESPACE"}},
+		{fn: semconv.K8SNodeName, envs: []string{"K8S_NODE_NAME", "NODE_NAME"}},
+		{fn: semconv.K8SContainerName, envs: []string{"K8S_CONTAINER_NAME", "CONTAINER_NAME"}},
+	} {
+		for _, env := range attr.envs {
+			if value := os.Getenv(env); value != "" {
+				attrs = append(attrs, attr.fn(value))
+				break
+			}
+		}
+	}
+	if len(attrs) == 0 {
+		return sdkresource.Empty(), nil
+	}
+
+	return sdkresource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
@@ -0,0 +1,65 @@
+package trace_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pixel8labs/logtrace/trace"
+)
+
+func TestInitTracer_Resource(t *testing.T) {
+	// Given the tracer initialized with the service info & running in a Kubernetes pod.
+	exporter := tracetest.NewInMemoryExporter()
+	resetTracer(t)
+	t.Setenv("K8S_POD_NAME", "api-7d9f8-abcde")
+	t.Setenv("POD_NAMESPACE", "payments")
+	t.Setenv("SERVICE_NAME", "overridden-by-option")
+	shutdown, err := trace.InitTracer(
+		trace.WithExporter(exporter),
+		trace.WithServiceName("service-name"),
+		trace.WithServiceVersion("1.2.3"),
+		trace.WithEnvironment("test"),
+	)
+	require.NoError(t, err)
+
+	// When we end a span.
+	_, span := trace.StartSpan(context.Background(), "test", "test-resource")
+	span.End()
+	forceFlush(t)
+	spans := exporter.GetSpans()
+	require.NoError(t, shutdown(context.Background()))
+
+	// Then the span carries the resource attributes.
+	require.Len(t, spans, 1)
+	attrs := map[attribute.Key]string{}
+	for _, kv := range spans[0].Resource.Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	assert.Equal(t, "service-name", attrs["service.name"])
+	assert.Equal(t, "1.2.3", attrs["service.version"])
+	assert.Equal(t, "test", attrs["deployment.environment"])
+	assert.Equal(t, "api-7d9f8-abcde", attrs["k8s.pod.name"])
+	assert.Equal(t, "payments", attrs["k8s.namespace.name"])
+	assert.NotEmpty(t, attrs["host.name"])
+	assert.NotEmpty(t, attrs["process.pid"])
+
+	// And the service info is exposed for the logs.
+	assert.Equal(t, trace.ServiceInfo{Name: "service-name", Version: "1.2.3", Environment: "test"}, trace.Service())
+}
+
+func TestInitTracer_ResourceFromEnv(t *testing.T) {
+	resetTracer(t)
+	t.Setenv("SERVICE_NAME", "from-service-name")
+	t.Setenv("OTEL_SERVICE_NAME", "from-otel")
+	t.Setenv("APP_ENV", "staging")
+
+	_, err := trace.InitTracer()
+	require.NoError(t, err)
+
+	assert.Equal(t, trace.ServiceInfo{Name: "from-otel", Environment: "staging"}, trace.Service())
+}
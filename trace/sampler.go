@@ -0,0 +1,216 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SamplingRule forces the sampling decision of the spans with the given name, regardless of the other samplers.
+type SamplingRule struct {
+	// SpanName matches the spans with this exact name, or ending with " "+SpanName,
+	// so "/healthcheck" matches the "GET /healthcheck" span of the echo middleware.
+	SpanName string
+	// Sample is whether the matching spans are sampled.
+	Sample bool
+}
+
+// AlwaysSampleSpan returns a rule sampling all the spans with the given name, see SamplingRule.
+func AlwaysSampleSpan(spanName string) SamplingRule {
+	return SamplingRule{SpanName: spanName, Sample: true}
+}
+
+// NeverSampleSpan returns a rule dropping all the spans with the given name, see SamplingRule.
+func NeverSampleSpan(spanName string) SamplingRule {
+	return SamplingRule{SpanName: spanName, Sample: false}
+}
+
+func (r SamplingRule) matches(spanName string) bool {
+	return spanName == r.SpanName || strings.HasSuffix(spanName, " "+r.SpanName)
+}
+
+// WithSampler sets the base sampler. Defaults to the OTEL_TRACES_SAMPLER & OTEL_TRACES_SAMPLER_ARG env vars,
+// or sampling everything if not set.
+func WithSampler(sampler sdktrace.Sampler) InitOptFn {
+	return func(config *initConfig) {
+		config.sampler = sampler
+	}
+}
+
+// WithRatioSampler samples the given ratio (0 to 1) of the new traces, and follows the parent decision
+// for the propagated ones. See WithSampler.
+func WithRatioSampler(ratio float64) InitOptFn {
+	return WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)))
+}
+
+// WithSamplingRules forces the sampling decision of the spans by name, e.g. never sample "/healthcheck"
+// but always sample "/checkout". The first matching rule wins, and takes precedence over the other samplers.
+func WithSamplingRules(rules ...SamplingRule) InitOptFn {
+	return func(config *initConfig) {
+		config.samplingRules = append(config.samplingRules, rules...)
+	}
+}
+
+// WithRateLimit caps the new traces sampled by the base sampler to perSecond traces per second.
+// The spans of the already sampled traces aren't limited, so the traces are never cut in the middle.
+func WithRateLimit(perSecond float64) InitOptFn {
+	return func(config *initConfig) {
+		config.rateLimit = perSecond
+	}
+}
+
+// newSampler combines the configured samplers: the rules first, then the base sampler, capped by the rate limit.
+func newSampler(cfg *initConfig) (sdktrace.Sampler, error) {
+	sampler := cfg.sampler
+	if sampler == nil {
+		var err error
+		sampler, err = samplerFromEnv()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.rateLimit > 0 {
+		sampler = newRateLimitSampler(sampler, cfg.rateLimit, time.Now)
+	}
+	if len(cfg.samplingRules) > 0 {
+		sampler = ruleSampler{rules: cfg.samplingRules, fallback: sampler}
+	}
+
+	return sampler, nil
+}
+
+// samplerFromEnv returns the sampler set by the standard OTEL_TRACES_SAMPLER & OTEL_TRACES_SAMPLER_ARG env vars,
+// or always sample if not set.
+func samplerFromEnv() (sdktrace.Sampler, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER")))
+	arg := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG"))
+
+	ratio := 1.0
+	if arg != "" && strings.HasSuffix(name, "traceidratio") {
+		var err error
+		ratio, err = strconv.ParseFloat(arg, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("trace: invalid OTEL_TRACES_SAMPLER_ARG %q, expected a ratio between 0 and 1", arg)
+		}
+	}
+
+	switch name {
+	case "", "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("trace: unsupported OTEL_TRACES_SAMPLER %q", name)
+	}
+}
+
+// ruleSampler forces the decision of the spans matching a rule, and delegates the others to the fallback.
+type ruleSampler struct {
+	rules    []SamplingRule
+	fallback sdktrace.Sampler
+}
+
+func (s ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, rule := range s.rules {
+		if !rule.matches(p.Name) {
+			continue
+		}
+		decision := sdktrace.Drop
+		if rule.Sample {
+			decision = sdktrace.RecordAndSample
+		}
+		return sdktrace.SamplingResult{
+			Decision:   decision,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+
+	return s.fallback.ShouldSample(p)
+}
+
+func (s ruleSampler) Description() string {
+	rules := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, fmt.Sprintf("%s=%t", rule.SpanName, rule.Sample))
+	}
+
+	return fmt.Sprintf("RuleSampler{rules:[%s],fallback:%s}", strings.Join(rules, ","), s.fallback.Description())
+}
+
+// rateLimitSampler caps the new traces sampled by the base sampler with a token bucket.
+type rateLimitSampler struct {
+	base      sdktrace.Sampler
+	perSecond float64
+	now       func() time.Time
+
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	last     time.Time
+}
+
+func newRateLimitSampler(base sdktrace.Sampler, perSecond float64, now func() time.Time) *rateLimitSampler {
+	// Allow a burst of a second worth of traces, at least one.
+	capacity := math.Max(perSecond, 1)
+
+	return &rateLimitSampler{
+		base:      base,
+		perSecond: perSecond,
+		now:       now,
+		tokens:    capacity,
+		capacity:  capacity,
+		last:      now(),
+	}
+}
+
+func (s *rateLimitSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	res := s.base.ShouldSample(p)
+	if res.Decision != sdktrace.RecordAndSample {
+		return res
+	}
+	// Only limit the new traces, the spans of an already sampled trace must follow it.
+	// An unsampled parent, e.g. a remote one with the default sampler, starts a new sampled trace here.
+	if trace.SpanContextFromContext(p.ParentContext).IsSampled() {
+		return res
+	}
+	if !s.take() {
+		res.Decision = sdktrace.Drop
+		res.Attributes = nil
+	}
+
+	return res
+}
+
+func (s *rateLimitSampler) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.tokens = math.Min(s.capacity, s.tokens+now.Sub(s.last).Seconds()*s.perSecond)
+	s.last = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+
+	return true
+}
+
+func (s *rateLimitSampler) Description() string {
+	return fmt.Sprintf("RateLimitSampler{%g/s,%s}", s.perSecond, s.base.Description())
+}
@@ -0,0 +1,188 @@
+package trace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lowTraceID is sampled by any ratio above 0, highTraceID only by a ratio of 1.
+var (
+	lowTraceID  = trace.TraceID{0: 0x01, 15: 0x01}
+	highTraceID = trace.TraceID{0: 0xff, 8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff}
+)
+
+func rootParams(name string, traceID trace.TraceID) sdktrace.SamplingParameters {
+	return sdktrace.SamplingParameters{ParentContext: context.Background(), TraceID: traceID, Name: name}
+}
+
+func childParams(name string, traceID trace.TraceID, sampled bool) sdktrace.SamplingParameters {
+	cfg := trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{0: 0x01}, Remote: true}
+	if sampled {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(cfg))
+
+	return sdktrace.SamplingParameters{ParentContext: ctx, TraceID: traceID, Name: name}
+}
+
+func newTestSampler(t *testing.T, opts ...InitOptFn) sdktrace.Sampler {
+	t.Helper()
+	cfg := &initConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	sampler, err := newSampler(cfg)
+	require.NoError(t, err)
+
+	return sampler
+}
+
+func TestNewSampler_Default(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "")
+	sampler := newTestSampler(t)
+
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("test", highTraceID)).Decision)
+}
+
+func TestNewSampler_Ratio(t *testing.T) {
+	// Given a ratio sampler of 50%.
+	sampler := newTestSampler(t, WithRatioSampler(0.5))
+
+	// Then the new traces are sampled by trace id.
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("test", lowTraceID)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(rootParams("test", highTraceID)).Decision)
+
+	// And the propagated traces follow the parent decision.
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(childParams("test", highTraceID, true)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(childParams("test", lowTraceID, false)).Decision)
+}
+
+func TestNewSampler_Rules(t *testing.T) {
+	// Given a sampler dropping everything except the checkout, and never the healthcheck.
+	sampler := newTestSampler(t,
+		WithSampler(sdktrace.NeverSample()),
+		WithSamplingRules(NeverSampleSpan("/healthcheck"), AlwaysSampleSpan("/checkout")),
+	)
+
+	// Then the rules take precedence, on the exact name or the route of "METHOD /route".
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("POST /checkout", highTraceID)).Decision)
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("/checkout", highTraceID)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(rootParams("GET /checkout/items", highTraceID)).Decision)
+
+	sampler = newTestSampler(t, WithSamplingRules(NeverSampleSpan("/healthcheck")))
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(rootParams("GET /healthcheck", lowTraceID)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(childParams("GET /healthcheck", lowTraceID, true)).Decision)
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("GET /users", lowTraceID)).Decision)
+	assert.Contains(t, sampler.Description(), "/healthcheck=false")
+}
+
+func TestRateLimitSampler(t *testing.T) {
+	// Given a sampler capped at 2 traces per second, with a fake clock.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sampler := newRateLimitSampler(sdktrace.AlwaysSample(), 2, func() time.Time { return now })
+	sample := func(params sdktrace.SamplingParameters) sdktrace.SamplingDecision {
+		return sampler.ShouldSample(params).Decision
+	}
+
+	// When more traces are started within the same second.
+	// Then only the first 2 are sampled.
+	assert.Equal(t, sdktrace.RecordAndSample, sample(rootParams("test", lowTraceID)))
+	assert.Equal(t, sdktrace.RecordAndSample, sample(rootParams("test", lowTraceID)))
+	assert.Equal(t, sdktrace.Drop, sample(rootParams("test", lowTraceID)))
+
+	// And the spans of the sampled traces aren't limited.
+	assert.Equal(t, sdktrace.RecordAndSample, sample(childParams("test", lowTraceID, true)))
+
+	// When half a second passes.
+	// Then 1 more trace is sampled.
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, sdktrace.RecordAndSample, sample(rootParams("test", lowTraceID)))
+	assert.Equal(t, sdktrace.Drop, sample(rootParams("test", lowTraceID)))
+
+	// When a long time passes.
+	// Then the burst is still capped to 1 second worth of traces.
+	now = now.Add(time.Hour)
+	assert.Equal(t, sdktrace.RecordAndSample, sample(rootParams("test", lowTraceID)))
+	assert.Equal(t, sdktrace.RecordAndSample, sample(rootParams("test", lowTraceID)))
+	assert.Equal(t, sdktrace.Drop, sample(rootParams("test", lowTraceID)))
+}
+
+func TestRateLimitSampler_UnsampledRemoteParent(t *testing.T) {
+	// Given a rate limited sampler sampling the unsampled remote parents, like the default one.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sampler := newRateLimitSampler(sdktrace.AlwaysSample(), 1, func() time.Time { return now })
+
+	// When the requests carry an unsampled traceparent.
+	// Then they're limited like the new traces.
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(childParams("test", lowTraceID, false)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(childParams("test", lowTraceID, false)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(rootParams("test", lowTraceID)).Decision)
+
+	// And the spans of the sampled traces still aren't.
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(childParams("test", lowTraceID, true)).Decision)
+}
+
+func TestRateLimitSampler_DroppedByBase(t *testing.T) {
+	// Given a rate limited ratio sampler.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sampler := newRateLimitSampler(sdktrace.TraceIDRatioBased(0.5), 1, func() time.Time { return now })
+
+	// Then the traces dropped by the ratio don't use the budget.
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(rootParams("test", highTraceID)).Decision)
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("test", lowTraceID)).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(rootParams("test", lowTraceID)).Decision)
+}
+
+func TestSamplerFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		sampler  string
+		arg      string
+		root     trace.TraceID
+		expected sdktrace.SamplingDecision
+		child    sdktrace.SamplingDecision
+	}{
+		{sampler: "always_on", root: highTraceID, expected: sdktrace.RecordAndSample, child: sdktrace.RecordAndSample},
+		{sampler: "always_off", root: lowTraceID, expected: sdktrace.Drop, child: sdktrace.Drop},
+		{sampler: "traceidratio", arg: "0.5", root: highTraceID, expected: sdktrace.Drop, child: sdktrace.Drop},
+		{sampler: "traceidratio", arg: "0.5", root: lowTraceID, expected: sdktrace.RecordAndSample, child: sdktrace.RecordAndSample},
+		{sampler: "parentbased_always_on", root: highTraceID, expected: sdktrace.RecordAndSample, child: sdktrace.Drop},
+		{sampler: "parentbased_always_off", root: lowTraceID, expected: sdktrace.Drop, child: sdktrace.Drop},
+		{sampler: "parentbased_traceidratio", arg: "0.5", root: lowTraceID, expected: sdktrace.RecordAndSample, child: sdktrace.Drop},
+		{sampler: "parentbased_traceidratio", root: highTraceID, expected: sdktrace.RecordAndSample, child: sdktrace.Drop},
+	} {
+		t.Run(tc.sampler+"="+tc.arg, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_SAMPLER", tc.sampler)
+			t.Setenv("OTEL_TRACES_SAMPLER_ARG", tc.arg)
+			sampler := newTestSampler(t)
+
+			assert.Equal(t, tc.expected, sampler.ShouldSample(rootParams("test", tc.root)).Decision)
+			// The child of an unsampled parent.
+			assert.Equal(t, tc.child, sampler.ShouldSample(childParams("test", tc.root, false)).Decision)
+		})
+	}
+}
+
+func TestSamplerFromEnv_Invalid(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "2")
+	_, err := InitTracer()
+	assert.ErrorContains(t, err, "OTEL_TRACES_SAMPLER_ARG")
+
+	t.Setenv("OTEL_TRACES_SAMPLER", "jaeger_remote")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "")
+	_, err = InitTracer()
+	assert.ErrorContains(t, err, "unsupported OTEL_TRACES_SAMPLER")
+}
+
+func TestNewSampler_OptionOverridesEnv(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_off")
+	sampler := newTestSampler(t, WithSampler(sdktrace.AlwaysSample()))
+
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(rootParams("test", highTraceID)).Decision)
+}
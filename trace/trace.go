@@ -4,24 +4,151 @@ package trace
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-logr/logr"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func InitTracer() {
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
+type initConfig struct {
+	// logger is where the OpenTelemetry internal logs & errors are written. Kept as-is (stderr) if nil.
+	logger *logr.Logger
+	// exporters are where the spans are exported, each with its own batch span processor.
+	// Read from the OTEL_* env vars if empty.
+	exporters []exporterOpener
+	// batchOpts are the options of the batch span processors.
+	batchOpts []sdktrace.BatchSpanProcessorOption
+	// serviceName, serviceVersion, environment & resourceAttrs are set on the resource, see newResource.
+	serviceName    string
+	serviceVersion string
+	environment    string
+	resourceAttrs  []attribute.KeyValue
+	// sampler, samplingRules & rateLimit are combined into the sampler of the provider, see newSampler.
+	sampler       sdktrace.Sampler
+	samplingRules []SamplingRule
+	rateLimit     float64
+	// propagators are the names of the propagators, see newPropagator.
+	propagators []string
+}
+
+type InitOptFn func(config *initConfig)
+
+// WithLogger writes the OpenTelemetry internal logs & errors (e.g. exporter failures) to the given logger.
+// To write them through the log package, use trace.WithLogger(log.Logr()).
+func WithLogger(logger logr.Logger) InitOptFn {
+	return func(config *initConfig) {
+		config.logger = &logger
+	}
+}
+
+// ShutdownFn flushes the pending spans & shuts the tracer provider down.
+type ShutdownFn func(ctx context.Context) error
+
+// provider is the tracer provider created by the last InitTracer, shut down by Shutdown.
+var provider atomic.Pointer[sdktrace.TracerProvider]
+
+// replacedShutdownTimeout is how long InitTracer waits for the replaced tracer provider to flush its spans.
+const replacedShutdownTimeout = 5 * time.Second
+
+// InitTracer sets up the global tracer provider & propagator.
+// Without exporter options, the spans are exported following the standard OTEL_* env vars,
+// or only used to propagate the trace ids if none is set.
+// The returned function flushes the pending spans & shuts the provider down, call it before exiting.
+// Calling InitTracer again shuts the previous provider down, once its pending spans are flushed.
+func InitTracer(opts ...InitOptFn) (ShutdownFn, error) {
+	cfg := &initConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.exporters) == 0 {
+		exporters, err := exportersFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		cfg.exporters = exporters
+	}
+
+	if cfg.logger != nil {
+		logger := *cfg.logger
+		otel.SetLogger(logger)
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+			logger.Error(err, "OpenTelemetry error")
+		}))
+	}
+
+	propagator, err := newPropagator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := newResource(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+	}
+	var exporters []sdktrace.SpanExporter
+	for _, open := range cfg.exporters {
+		exporter, err := open(context.Background())
+		if err != nil {
+			for _, e := range exporters {
+				_ = e.Shutdown(context.Background())
+			}
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(exporter, cfg.batchOpts...))
+	}
+
+	tp := sdktrace.NewTracerProvider(tpOpts...)
 
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTextMapPropagator(propagator)
+	info := serviceInfoFromResource(res)
+	service.Store(&info)
+	if prev := provider.Swap(tp); prev != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), replacedShutdownTimeout)
+		defer cancel()
+		if err := shutdownProvider(ctx, prev); err != nil {
+			otel.Handle(err)
+		}
+	}
+
+	return func(ctx context.Context) error {
+		return shutdownProvider(ctx, tp)
+	}, nil
+}
+
+// Shutdown flushes the pending spans & shuts down the tracer provider created by the last InitTracer.
+// It's a no-op if InitTracer wasn't called.
+func Shutdown(ctx context.Context) error {
+	tp := provider.Load()
+	if tp == nil {
+		return nil
+	}
+
+	return shutdownProvider(ctx, tp)
+}
+
+func shutdownProvider(ctx context.Context, tp *sdktrace.TracerProvider) error {
+	return errors.Join(tp.ForceFlush(ctx), tp.Shutdown(ctx))
 }
 
-func StartSpan(ctx context.Context, serviceName string, spanName string) (context.Context, trace.Span) {
-	return otel.Tracer(serviceName).Start(ctx, spanName)
+func StartSpan(ctx context.Context, serviceName string, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(serviceName).Start(ctx, spanName, opts...)
 }
 
 func SpanFromContext(ctx context.Context) trace.Span {
@@ -53,3 +180,13 @@ func InjectTraceToMap(ctx context.Context, mapStringToString map[string]string)
 func ExtractTraceFromMap(ctx context.Context, mapStringToString map[string]string) context.Context {
 	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(mapStringToString))
 }
+
+// InjectTraceToHeader injects the trace of ctx into the HTTP headers, with the global propagator.
+func InjectTraceToHeader(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// ExtractTraceFromHeader returns ctx with the trace (and baggage) of the HTTP headers, with the global propagator.
+func ExtractTraceFromHeader(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
@@ -2,14 +2,19 @@ package trace_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"github.com/pixel8labs/logtrace/trace"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func init() {
-	trace.InitTracer()
+	if _, err := trace.InitTracer(); err != nil {
+		panic(err)
+	}
 }
 
 func TestInjectTraceToMapAndExtractTraceFromMap(t *testing.T) {
@@ -33,3 +38,45 @@ func TestInjectTraceToMapAndExtractTraceFromMap(t *testing.T) {
 	assert.Equal(t, originalTraceId, newTraceId)
 	assert.Equal(t, originalSpanId, newSpanId)
 }
+
+// recordingExporter records the exported spans & whether it was shut down.
+type recordingExporter struct {
+	mu       sync.Mutex
+	spans    []string
+	shutdown bool
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, span := range spans {
+		e.spans = append(e.spans, span.Name())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func TestInitTracer_ShutsDownReplacedProvider(t *testing.T) {
+	// Given a tracer with a span pending export.
+	replaced := &recordingExporter{}
+	_, err := trace.InitTracer(trace.WithExporter(replaced))
+	require.NoError(t, err)
+	_, span := trace.StartSpan(context.Background(), "test", "pending")
+	span.End()
+
+	// When the tracer is initialized again.
+	_, err = trace.InitTracer()
+	require.NoError(t, err)
+
+	// Then the replaced provider flushed its spans & was shut down.
+	replaced.mu.Lock()
+	defer replaced.mu.Unlock()
+	assert.Equal(t, []string{"pending"}, replaced.spans)
+	assert.True(t, replaced.shutdown)
+}